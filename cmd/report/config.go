@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/query"
+	"job-hunter/internal/reporter"
+)
+
+// Config is the file read with -config: the searches to run, each with its
+// own recipients, and how to send the reports.
+type Config struct {
+	// DataDir is used when -data-dir isn't given.
+	DataDir   string           `yaml:"data_dir" toml:"data_dir"`
+	SMTP      SMTPConfig       `yaml:"smtp" toml:"smtp"`
+	Profiles  []Profile        `yaml:"profiles" toml:"profiles"`
+	Notifiers []NotifierConfig `yaml:"notifiers" toml:"notifiers"`
+}
+
+// SMTPConfig is where reports are sent from. Empty fields fall back to the
+// SMTP_HOST, SMTP_USERNAME, SMTP_PASSWORD and FROM_EMAIL variables.
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	From     string `yaml:"from" toml:"from"`
+	// Retries and RetryDelay are as for notifiers, for emails the server
+	// defers or that don't reach it.
+	Retries    *int   `yaml:"retries" toml:"retries"`
+	RetryDelay string `yaml:"retry_delay" toml:"retry_delay"`
+
+	retry crawler.RetryPolicy
+}
+
+// NotifierConfig is a chat channel or webhook the reports are posted to,
+// besides the profiles' email recipients.
+type NotifierConfig struct {
+	Name   string `yaml:"name" toml:"name"` // For logs; defaults to the type
+	Type   string `yaml:"type" toml:"type"` // slack, discord or webhook
+	URL    string `yaml:"url" toml:"url"`
+	Secret string `yaml:"secret" toml:"secret"` // Signs webhook requests
+	// Sections of the reports to post, as in reporter.Sections; empty
+	// means all of them.
+	Sections []string `yaml:"sections" toml:"sections"`
+	// Profiles whose reports are posted; empty means every profile.
+	Profiles []string `yaml:"profiles" toml:"profiles"`
+	// Retries is the number of extra attempts after a post fails, and
+	// RetryDelay the wait before the first, doubling after each.
+	Retries    *int   `yaml:"retries" toml:"retries"`
+	RetryDelay string `yaml:"retry_delay" toml:"retry_delay"`
+
+	channel reporter.Channel
+}
+
+func (n *NotifierConfig) String() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.Type
+}
+
+// covers reports whether the notifier receives a profile's reports.
+func (n *NotifierConfig) covers(profile string) bool {
+	return len(n.Profiles) == 0 || slices.Contains(n.Profiles, profile)
+}
+
+// init validates the notifier and builds its channel.
+func (n *NotifierConfig) init() error {
+	if n.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if n.Secret != "" && n.Type != "webhook" {
+		return fmt.Errorf("secret only applies to webhook notifiers")
+	}
+
+	retry, err := retryPolicy(reporter.DefaultWebhookRetry(), n.Retries, n.RetryDelay)
+	if err != nil {
+		return err
+	}
+
+	switch n.Type {
+	case "slack":
+		n.channel.Notifier = reporter.NewSlackNotifier(n.URL, retry)
+	case "discord":
+		n.channel.Notifier = reporter.NewDiscordNotifier(n.URL, retry)
+	case "webhook":
+		n.channel.Notifier = reporter.NewWebhookNotifier(n.URL, n.Secret, retry)
+	default:
+		return fmt.Errorf("type %q must be slack, discord or webhook", n.Type)
+	}
+	for _, name := range n.Sections {
+		section, err := reporter.ParseSection(name)
+		if err != nil {
+			return err
+		}
+		n.channel.Sections = append(n.channel.Sections, section)
+	}
+	return nil
+}
+
+// retryPolicy returns policy with the retries and retry_delay of a notifier
+// or the SMTP settings applied.
+func retryPolicy(policy crawler.RetryPolicy, retries *int, retryDelay string) (crawler.RetryPolicy, error) {
+	if retries != nil {
+		if *retries < 0 {
+			return policy, fmt.Errorf("retries must not be negative")
+		}
+		policy.MaxRetries = *retries
+	}
+	if retryDelay != "" {
+		d, err := time.ParseDuration(retryDelay)
+		if err != nil || d <= 0 {
+			return policy, fmt.Errorf("retry_delay %q must be a positive duration", retryDelay)
+		}
+		policy.BaseDelay = d
+		policy.MaxDelay = max(policy.MaxDelay, d)
+	}
+	return policy, nil
+}
+
+// Profile is a named search.
+type Profile struct {
+	Name     string `yaml:"name" toml:"name"`
+	Title    string `yaml:"title" toml:"title"`
+	Location string `yaml:"location" toml:"location"`
+
+	// Filters, as in crawler.JobSearchParams
+	Keywords       []string `yaml:"keywords" toml:"keywords"`
+	Exclude        []string `yaml:"exclude" toml:"exclude"`
+	Workplace      string   `yaml:"workplace" toml:"workplace"`
+	EmploymentType string   `yaml:"employment_type" toml:"employment_type"`
+	Experience     string   `yaml:"experience" toml:"experience"`
+	PostedWithin   string   `yaml:"posted_within" toml:"posted_within"` // A duration such as "72h"
+	Radius         int      `yaml:"radius" toml:"radius"`
+	MinSalary      float64  `yaml:"min_salary" toml:"min_salary"`
+	Query          string   `yaml:"query" toml:"query"`
+
+	// Sources to search; empty means those of -sources, or all of them.
+	Sources []string `yaml:"sources" toml:"sources"`
+	// Recipients are emailed the whole report. They can be left out when
+	// a notifier covers the profile.
+	Recipients []string `yaml:"recipients" toml:"recipients"`
+	// Schedule is how often the profile runs: "hourly", "daily", "weekly"
+	// or a duration. Runs in between skip it. Empty means every run.
+	Schedule string `yaml:"schedule" toml:"schedule"`
+	// Namespace is the subdirectory of the data directory holding the
+	// profile's job history. It defaults to the name; profiles sharing
+	// one share their history.
+	Namespace string `yaml:"namespace" toml:"namespace"`
+
+	params   crawler.JobSearchParams
+	interval time.Duration
+}
+
+// String returns the profile's name, or its title for the profile made up
+// by the flags.
+func (p *Profile) String() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Title
+}
+
+// scheduleSlack lets a profile run a little early, since scheduled
+// workflows don't start on the minute.
+const scheduleSlack = 15 * time.Minute
+
+var schedules = map[string]time.Duration{"hourly": time.Hour, "daily": 24 * time.Hour, "weekly": 7 * 24 * time.Hour}
+
+// namePattern is what profile names and namespaces may look like, so they
+// can be used as directory names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// LoadConfig reads a YAML or TOML config file, telling them apart by
+// extension, replaces ${VAR} with the environment variable VAR in every
+// string, and validates the profiles.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&config)
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&config)
+	default:
+		return nil, fmt.Errorf("%s: unknown config format %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := expandEnv(reflect.ValueOf(&config).Elem()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("no profiles")
+	}
+	seen := make(map[string]bool)
+	for i := range c.Profiles {
+		p := &c.Profiles[i]
+		if !namePattern.MatchString(p.Name) {
+			return fmt.Errorf("profile %d: name %q must be letters, digits, '.', '_' and '-'", i+1, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("profile %q is defined twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Namespace == "" {
+			p.Namespace = p.Name
+		}
+		if err := p.init(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+
+	var err error
+	if c.SMTP.retry, err = retryPolicy(reporter.DefaultEmailRetry(), c.SMTP.Retries, c.SMTP.RetryDelay); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	for i := range c.Notifiers {
+		n := &c.Notifiers[i]
+		if err := n.init(); err != nil {
+			return fmt.Errorf("notifier %d (%s): %w", i+1, n, err)
+		}
+		for _, name := range n.Profiles {
+			if !seen[name] {
+				return fmt.Errorf("notifier %d (%s): unknown profile %q", i+1, n, name)
+			}
+		}
+	}
+	for _, p := range c.Profiles {
+		if len(p.Recipients) == 0 && !slices.ContainsFunc(c.Notifiers, func(n NotifierConfig) bool { return n.covers(p.Name) }) {
+			return fmt.Errorf("profile %q: no recipients, and no notifier posts its reports", p.Name)
+		}
+	}
+	return nil
+}
+
+// init validates the profile and works out its search and schedule.
+func (p *Profile) init() error {
+	if p.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if p.Namespace != "" && !namePattern.MatchString(p.Namespace) {
+		return fmt.Errorf("namespace %q must be letters, digits, '.', '_' and '-'", p.Namespace)
+	}
+
+	p.params = crawler.JobSearchParams{
+		Title:           p.Title,
+		Location:        p.Location,
+		Keywords:        p.Keywords,
+		ExcludeKeywords: p.Exclude,
+		Workplace:       p.Workplace,
+		EmploymentType:  p.EmploymentType,
+		ExperienceLevel: p.Experience,
+		Radius:          p.Radius,
+		MinSalary:       p.MinSalary,
+	}
+	if p.PostedWithin != "" {
+		d, err := time.ParseDuration(p.PostedWithin)
+		if err != nil {
+			return fmt.Errorf("posted_within: %w", err)
+		}
+		p.params.PostedWithin = d
+	}
+	if p.Query != "" {
+		q, err := query.Parse(p.Query)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+		p.params.Query = q
+	}
+	if err := p.params.Validate(); err != nil {
+		return err
+	}
+
+	if p.Schedule != "" {
+		interval, ok := schedules[p.Schedule]
+		if !ok {
+			d, err := time.ParseDuration(p.Schedule)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("schedule %q must be hourly, daily, weekly or a duration", p.Schedule)
+			}
+			interval = d
+		}
+		p.interval = interval
+	}
+	return nil
+}
+
+// due reports whether a profile last run at lastRun should run at now.
+func (p *Profile) due(lastRun, now time.Time) bool {
+	return p.interval == 0 || lastRun.IsZero() || now.Sub(lastRun) >= p.interval-scheduleSlack
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} in the strings under v with the environment
+// variable VAR. A variable that isn't set is an error, so a missing secret
+// doesn't go unnoticed.
+func expandEnv(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		var missing []string
+		expanded := envPattern.ReplaceAllStringFunc(v.String(), func(ref string) string {
+			name := envPattern.FindStringSubmatch(ref)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+		}
+		v.SetString(expanded)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				if err := expandEnv(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := expandEnv(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/reporter"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_SMTP_PASSWORD", "secret")
+
+	yamlConfig := `
+data_dir: /var/lib/job-hunter
+smtp:
+  host: smtp.example.com
+  port: 465
+  password: ${TEST_SMTP_PASSWORD}
+  retries: 5
+profiles:
+  - name: go-remote
+    title: golang developer
+    keywords: [kubernetes]
+    workplace: remote
+    posted_within: 72h
+    query: title:senior AND NOT intern
+    recipients: [me@example.com]
+    schedule: daily
+  - name: rust
+    title: rust engineer
+    location: Berlin
+    sources: [LinkedIn, Indeed]
+    recipients: [me@example.com, friend@example.com]
+    namespace: shared
+`
+	tomlConfig := `
+data_dir = "/var/lib/job-hunter"
+
+[smtp]
+host = "smtp.example.com"
+port = 465
+password = "${TEST_SMTP_PASSWORD}"
+retries = 5
+
+[[profiles]]
+name = "go-remote"
+title = "golang developer"
+keywords = ["kubernetes"]
+workplace = "remote"
+posted_within = "72h"
+query = "title:senior AND NOT intern"
+recipients = ["me@example.com"]
+schedule = "daily"
+
+[[profiles]]
+name = "rust"
+title = "rust engineer"
+location = "Berlin"
+sources = ["LinkedIn", "Indeed"]
+recipients = ["me@example.com", "friend@example.com"]
+namespace = "shared"
+`
+	for name, content := range map[string]string{"config.yaml": yamlConfig, "config.toml": tomlConfig} {
+		config, err := LoadConfig(writeConfig(t, name, content))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+			continue
+		}
+		if config.DataDir != "/var/lib/job-hunter" || config.SMTP.Port != 465 {
+			t.Errorf("%s: expected the data dir and SMTP port to be read, got %q and %d", name, config.DataDir, config.SMTP.Port)
+		}
+		if config.SMTP.Password != "secret" {
+			t.Errorf("%s: expected the password from the environment, got %q", name, config.SMTP.Password)
+		}
+		if retry := config.SMTP.retry; retry.MaxRetries != 5 || retry.BaseDelay != reporter.DefaultEmailRetry().BaseDelay {
+			t.Errorf("%s: expected 5 email retries with the default delay, got %+v", name, retry)
+		}
+		if len(config.Profiles) != 2 {
+			t.Fatalf("%s: expected 2 profiles, got %d", name, len(config.Profiles))
+		}
+
+		goRemote, rust := config.Profiles[0], config.Profiles[1]
+		if goRemote.params.Workplace != crawler.WorkplaceRemote || goRemote.params.PostedWithin != 72*time.Hour || goRemote.params.Query == nil {
+			t.Errorf("%s: expected the filters to be parsed, got %+v", name, goRemote.params)
+		}
+		if goRemote.interval != 24*time.Hour {
+			t.Errorf("%s: expected a daily schedule, got %v", name, goRemote.interval)
+		}
+		if goRemote.Namespace != "go-remote" {
+			t.Errorf("%s: expected the namespace to default to the name, got %q", name, goRemote.Namespace)
+		}
+		if rust.Namespace != "shared" || rust.interval != 0 || len(rust.Sources) != 2 || len(rust.Recipients) != 2 {
+			t.Errorf("%s: expected the second profile as written, got %+v", name, rust)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	os.Unsetenv("TEST_UNSET_VARIABLE")
+
+	for _, tt := range []struct {
+		name, content, want string
+	}{
+		{"config.json", `{}`, "unknown config format"},
+		{"config.yaml", "profiles: []", "no profiles"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n    salary: 100", "salary"},
+		{"config.yaml", "profiles:\n  - name: a b\n    title: go\n    recipients: [me@example.com]", "name"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n  - name: a\n    title: rust\n    recipients: [me@example.com]", "defined twice"},
+		{"config.yaml", "profiles:\n  - name: a\n    recipients: [me@example.com]", "title is required"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go", "recipient"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n    workplace: office", "workplace"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n    query: (golang", "query"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n    schedule: fortnightly", "schedule"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]\n    namespace: ../b", "namespace"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\nnotifiers:\n  - type: slack\n    url: https://hooks.slack.com/x\n    profiles: [b]", "unknown profile"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\nnotifiers:\n  - type: teams\n    url: https://example.com", "type"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\nnotifiers:\n  - type: slack\n    url: https://hooks.slack.com/x\n    sections: [salaries]", "section"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\nnotifiers:\n  - type: discord\n    url: https://discord.com/x\n    secret: s", "secret"},
+		{"config.yaml", "profiles:\n  - name: a\n    title: go\nnotifiers:\n  - type: webhook", "url is required"},
+		{"config.yaml", "smtp:\n  retry_delay: soon\nprofiles:\n  - name: a\n    title: go\n    recipients: [me@example.com]", "smtp: retry_delay"},
+		{"config.toml", "[[profiles]]\nname = \"a\"\ntitle = \"go\"\nrecipients = [\"${TEST_UNSET_VARIABLE}\"]", "TEST_UNSET_VARIABLE is not set"},
+	} {
+		_, err := LoadConfig(writeConfig(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected an error mentioning %q, got %v", tt.content, tt.want, err)
+		}
+	}
+}
+
+func TestProfileDue(t *testing.T) {
+	now := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+	daily := Profile{interval: 24 * time.Hour}
+	for _, tt := range []struct {
+		profile Profile
+		lastRun time.Time
+		want    bool
+	}{
+		{Profile{}, now.Add(-time.Minute), true},
+		{daily, time.Time{}, true},
+		{daily, now.Add(-23 * time.Hour), false},
+		{daily, now.Add(-24*time.Hour + 5*time.Minute), true},
+		{daily, now.Add(-25 * time.Hour), true},
+	} {
+		if got := tt.profile.due(tt.lastRun, now); got != tt.want {
+			t.Errorf("Expected due=%v for interval %v and last run %v, got %v", tt.want, tt.profile.interval, tt.lastRun, got)
+		}
+	}
+}
+
+func TestLoadConfigNotifiers(t *testing.T) {
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T0/B0/x")
+
+	config, err := LoadConfig(writeConfig(t, "config.yaml", `
+profiles:
+  - name: go-remote
+    title: golang developer
+  - name: rust
+    title: rust engineer
+    recipients: [me@example.com]
+notifiers:
+  - name: team
+    type: slack
+    url: ${TEST_SLACK_WEBHOOK_URL}
+    sections: [new, reposted]
+    profiles: [go-remote]
+    retries: 0
+  - type: webhook
+    url: https://example.com/hooks/jobs
+    secret: s3cret
+    retry_delay: 5s
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	slack, webhook := config.Notifiers[0], config.Notifiers[1]
+	if _, ok := slack.channel.Notifier.(*reporter.SlackNotifier); !ok {
+		t.Errorf("Expected a Slack notifier, got %T", slack.channel.Notifier)
+	}
+	if len(slack.channel.Sections) != 2 || slack.channel.Sections[0] != reporter.SectionNew {
+		t.Errorf("Expected the new and reposted sections, got %v", slack.channel.Sections)
+	}
+	if !slack.covers("go-remote") || slack.covers("rust") || !webhook.covers("rust") {
+		t.Error("Expected the Slack notifier to cover go-remote only, and the webhook every profile")
+	}
+	if n, ok := webhook.channel.Notifier.(*reporter.WebhookNotifier); !ok || n.Secret != "s3cret" {
+		t.Errorf("Expected a signed webhook notifier, got %#v", webhook.channel.Notifier)
+	}
+	if slack.String() != "team" || webhook.String() != "webhook" {
+		t.Errorf("Expected the notifiers to be named team and webhook, got %s and %s", &slack, &webhook)
+	}
+}
@@ -6,29 +6,101 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
 	"job-hunter/internal/reporter"
+	"job-hunter/internal/store"
 )
 
+// searchFlags describe a single search; -config replaces them.
+var searchFlags = []string{"title", "location", "email", "keywords", "exclude", "workplace", "employment-type", "experience", "posted-within", "radius", "query", "min-salary"}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	configPath := flag.String("config", "", "YAML or TOML file of search profiles to run instead of a single search")
+	profileNames := flag.String("profiles", "", "Comma-separated profiles of -config to run regardless of their schedules (default: every profile that is due)")
 	title := flag.String("title", "", "Job title to search for")
 	location := flag.String("location", "", "Job location")
 	email := flag.String("email", "", "Email address to send report to")
 	dataDir := flag.String("data-dir", "", "Directory to store job data")
+	concurrency := flag.Int("concurrency", 4, "Maximum number of sources crawled in parallel")
+	sources := flag.String("sources", "", "Comma-separated list of sources to search (default: all registered)")
+	maxPages := flag.Int("max-pages", 5, "Maximum result pages fetched per source")
+	maxJobs := flag.Int("max-jobs", 250, "Maximum jobs collected per source")
+	proxy := flag.String("proxy", "", "HTTP(S) or SOCKS5 proxy URL for all crawler requests")
+	respectRobots := flag.Bool("respect-robots", false, "Skip URLs disallowed by each site's robots.txt and honor its Crawl-delay")
+	robotsContact := flag.String("robots-contact", "", "URL added to the User-Agent with -respect-robots, where site owners can learn about the crawler")
+	cacheDir := flag.String("cache-dir", "", "Directory for cached HTTP responses (default: no cache)")
+	cacheTTL := flag.Duration("cache-ttl", 15*time.Minute, "How long cached responses are reused before revalidating")
+	careersURLs := flag.String("careers-urls", "", "Comma-separated company careers pages publishing JobPosting JSON-LD, read by the Careers source")
+	enrich := flag.Bool("enrich", false, "Read each job's detail page for its full description, salary and posted date")
+	enrichMax := flag.Int("enrich-max", 100, "Maximum detail pages read per search")
+	selectorsDir := flag.String("selectors-dir", "", "Directory of selector spec files overriding the built-in ones")
+	sourceTimeout := flag.Duration("source-timeout", crawler.DefaultConfig().SourceTimeout, "Deadline for each individual source")
+	keywords := flag.String("keywords", "", "Comma-separated keywords every job must mention")
+	exclude := flag.String("exclude", "", "Comma-separated keywords no job may mention")
+	workplace := flag.String("workplace", "", "Only remote, hybrid or onsite jobs")
+	employmentType := flag.String("employment-type", "", "Only full_time, part_time, contractor, temporary or intern jobs")
+	experience := flag.String("experience", "", "Only internship, entry, mid, senior, director or executive jobs")
+	postedWithin := flag.Duration("posted-within", 0, "Only jobs posted within this long, e.g. 72h (0: any)")
+	radius := flag.Int("radius", 0, "Search radius around the location in miles, for sources that support it (0: the site's default)")
+	queryString := flag.String("query", "", `Boolean query every job must match, e.g. '(golang OR rust) AND NOT intern AND title:senior'`)
+	minSalary := flag.Float64("min-salary", 0, "Drop jobs whose yearly salary is known to be lower")
+	closeAfter := flag.Int("close-after", 3, "Runs in a row a job must be missing from, while its source succeeds, before it is reported closed (0: never)")
 	flag.Parse()
 
-	log.Printf("Starting job search with title=%s, location=%s", *title, *location)
+	var config *Config
+	if *configPath != "" {
+		flag.Visit(func(f *flag.Flag) {
+			if slices.Contains(searchFlags, f.Name) {
+				log.Fatalf("-%s can't be used with -config; set it in a profile instead", f.Name)
+			}
+		})
+		var err error
+		if config, err = LoadConfig(*configPath); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		log.Printf("Loaded %d profiles from %s", len(config.Profiles), *configPath)
+		if *dataDir == "" {
+			*dataDir = config.DataDir
+		}
+	} else {
+		log.Printf("Starting job search with title=%s, location=%s", *title, *location)
 
-	if *title == "" {
-		log.Fatal("Job title is required")
-	}
+		if *title == "" {
+			log.Fatal("Job title is required")
+		}
 
-	if *email == "" {
-		log.Fatal("Email address is required")
+		if *email == "" {
+			log.Fatal("Email address is required")
+		}
+
+		// The flags make up a single unnamed profile, kept at the root
+		// of the data directory.
+		profile := Profile{
+			Title:          *title,
+			Location:       *location,
+			Keywords:       crawler.SplitList(*keywords),
+			Exclude:        crawler.SplitList(*exclude),
+			Workplace:      *workplace,
+			EmploymentType: *employmentType,
+			Experience:     *experience,
+			Radius:         *radius,
+			MinSalary:      *minSalary,
+			Query:          *queryString,
+			Recipients:     []string{*email},
+		}
+		if *postedWithin > 0 {
+			profile.PostedWithin = postedWithin.String()
+		}
+		if err := profile.init(); err != nil {
+			log.Fatalf("Invalid search: %v", err)
+		}
+		config = &Config{Profiles: []Profile{profile}, SMTP: SMTPConfig{retry: reporter.DefaultEmailRetry()}}
 	}
 
 	if *dataDir == "" {
@@ -44,61 +116,230 @@ func main() {
 	}
 
 	// Initialize crawler
-	c := crawler.NewJobCrawler()
+	crawlerConfig := crawler.DefaultConfig()
+	crawlerConfig.Concurrency = *concurrency
+	crawlerConfig.SourceTimeout = *sourceTimeout
+	crawlerConfig.Pagination.MaxPages = *maxPages
+	crawlerConfig.Pagination.MaxJobs = *maxJobs
+	crawlerConfig.HTTP.ProxyURL = *proxy
+	crawlerConfig.HTTP.RespectRobots = *respectRobots
+	crawlerConfig.HTTP.RobotsContact = *robotsContact
+	crawlerConfig.HTTP.Cache.Dir = *cacheDir
+	crawlerConfig.HTTP.Cache.DefaultTTL = *cacheTTL
+	crawlerConfig.SelectorsDir = *selectorsDir
+	crawlerConfig.Enrich.Enabled = *enrich
+	crawlerConfig.Enrich.MaxJobs = *enrichMax
+	crawlerConfig.Enrich.Cache = crawler.NewFileEnrichmentCache(filepath.Join(*dataDir, "enriched_jobs.json"), 30*24*time.Hour)
+	if *careersURLs != "" {
+		crawlerConfig.Sources = map[string]crawler.SourceConfig{
+			"Careers": {URLs: crawler.SplitList(*careersURLs)},
+		}
+	}
+	if *sources != "" {
+		crawlerConfig.EnabledSources = crawler.SplitList(*sources)
+	}
+	// Profiles share rate limits and circuit breakers.
+	clients, err := crawler.NewClientFactory(crawlerConfig.HTTP)
+	if err != nil {
+		log.Fatalf("Failed to initialize crawler: %v", err)
+	}
+	crawlerConfig.Clients = clients
 
-	// Search for jobs
-	params := crawler.JobSearchParams{
-		Title:    *title,
-		Location: *location,
+	storeOptions := store.DefaultOptions()
+	storeOptions.CloseAfter = *closeAfter
+	r := runner{dataDir: *dataDir, crawlerConfig: crawlerConfig, storeOptions: storeOptions}
+
+	selected := crawler.SplitList(*profileNames)
+	for _, name := range selected {
+		if !slices.ContainsFunc(config.Profiles, func(p Profile) bool { return p.Name == name }) {
+			log.Fatalf("Unknown profile %q", name)
+		}
+	}
+
+	// Run every profile, gathering the reports of each recipient
+	date := time.Now()
+	var recipients []string
+	reports := make(map[string][]reporter.JobReport)
+	profileReports := make(map[string]reporter.JobReport)
+	failed := 0
+	for i := range config.Profiles {
+		profile := &config.Profiles[i]
+		if len(selected) > 0 && !slices.Contains(selected, profile.Name) {
+			continue
+		}
+		report, err := r.run(context.Background(), profile, len(selected) > 0)
+		if err != nil {
+			log.Printf("Search %q failed: %v", profile, err)
+			failed++
+			continue
+		}
+		if report == nil {
+			continue
+		}
+		report.Date = date
+		profileReports[profile.Name] = *report
+		for _, recipient := range profile.Recipients {
+			if _, ok := reports[recipient]; !ok {
+				recipients = append(recipients, recipient)
+			}
+			reports[recipient] = append(reports[recipient], *report)
+		}
+	}
+
+	// Send email
+	emailConfig := reporter.EmailConfig{
+		SMTPHost:     firstNonEmpty(config.SMTP.Host, os.Getenv("SMTP_HOST")),
+		SMTPPort:     587, // Default port for TLS
+		SMTPUsername: firstNonEmpty(config.SMTP.Username, os.Getenv("SMTP_USERNAME")),
+		SMTPPassword: firstNonEmpty(config.SMTP.Password, os.Getenv("SMTP_PASSWORD")),
+		FromEmail:    firstNonEmpty(config.SMTP.From, os.Getenv("FROM_EMAIL")),
+	}
+	if config.SMTP.Port != 0 {
+		emailConfig.SMTPPort = config.SMTP.Port
+	}
+	for _, recipient := range recipients {
+		emailConfig.ToEmail = recipient
+		log.Printf("Sending email report to %s", recipient)
+		if err := reporter.NewEmailNotifier(emailConfig, config.SMTP.retry).Notify(context.Background(), date, reports[recipient]); err != nil {
+			log.Printf("Failed to send email report to %s: %v", recipient, err)
+			failed++
+			continue
+		}
+		log.Printf("Email sent successfully")
+	}
+
+	// Post to the notifiers, each the reports of the profiles it covers
+	for i := range config.Notifiers {
+		notifier := &config.Notifiers[i]
+		var notified []reporter.JobReport
+		for _, profile := range config.Profiles {
+			if report, ok := profileReports[profile.Name]; ok && notifier.covers(profile.Name) {
+				notified = append(notified, report)
+			}
+		}
+		if len(notified) == 0 {
+			continue
+		}
+		log.Printf("Posting %d reports to %s", len(notified), notifier)
+		if err := notifier.channel.Notify(context.Background(), date, notified); err != nil {
+			log.Printf("Failed to post to %s: %v", notifier, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d searches or notifications failed", failed)
+	}
+}
+
+// runner runs the search of a profile and records it in the profile's
+// namespace.
+type runner struct {
+	dataDir       string
+	crawlerConfig crawler.Config
+	storeOptions  store.Options
+}
+
+// run searches for a profile's jobs and returns its report, or nil if the
+// profile isn't due yet and force isn't set.
+func (r runner) run(ctx context.Context, profile *Profile, force bool) (*reporter.JobReport, error) {
+	dir := filepath.Join(r.dataDir, profile.Namespace)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	jobStore, err := store.Open(filepath.Join(dir, "jobs.db"), r.storeOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer jobStore.Close()
+	importPreviousJobs(jobStore, filepath.Join(dir, "previous_jobs.txt"))
+
+	params := profile.params
+	if !force {
+		lastRun, err := jobStore.LastRun(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if !profile.due(lastRun, time.Now()) {
+			log.Printf("Skipping %q, last run at %s", profile, lastRun.Format(time.RFC3339))
+			return nil, nil
+		}
+	}
+
+	crawlerConfig := r.crawlerConfig
+	if len(profile.Sources) > 0 {
+		crawlerConfig.EnabledSources = profile.Sources
+	}
+	c, err := crawler.NewJobCrawler(crawlerConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Searching for jobs...")
-	jobs, err := c.SearchJobs(context.Background(), params)
+	// Search for jobs
+	log.Printf("Searching for jobs for %q...", profile)
+	startedAt := time.Now()
+	result, err := c.SearchJobs(ctx, params)
 	if err != nil {
-		log.Fatalf("Failed to search jobs: %v", err)
+		return nil, err
 	}
+	jobs := result.Jobs
 	log.Printf("Found %d jobs", len(jobs))
 	for i, job := range jobs {
 		log.Printf("Job %d: %s at %s (%s)", i+1, job.Title, job.Company, job.Source)
 	}
 
-	// Load previous jobs
-	prevJobsFile := filepath.Join(*dataDir, "previous_jobs.txt")
-	prevJobs, err := reporter.LoadPreviousJobs(prevJobsFile)
+	// Compare each source with its recent history to catch selector drift
+	monitor := health.NewMonitor(filepath.Join(dir, "source_health.json"), health.DefaultOptions())
+	alarms, err := monitor.Record(params, result)
 	if err != nil {
-		log.Printf("Warning: Failed to load previous jobs: %v", err)
+		log.Printf("Warning: Failed to record source health: %v", err)
 	}
 
-	// Find new jobs
-	newJobs := reporter.FindNewJobs(prevJobs, jobs)
-
-	// Save current jobs for next run
-	if err := reporter.SaveJobsToFile(jobs, prevJobsFile); err != nil {
-		log.Printf("Warning: Failed to save jobs: %v", err)
+	// Record the run, finding which jobs are new, reposted or closed
+	run, changes, err := jobStore.RecordRun(ctx, params, startedAt, result)
+	if err != nil {
+		log.Printf("Warning: Failed to record jobs: %v", err)
+	} else {
+		log.Printf("Recorded run %d: %d new, %d reposted, %d closed", run.ID, run.NewCount, run.RepostedCount, run.ClosedCount)
 	}
 
-	// Create report
-	report := reporter.JobReport{
-		Date:     time.Now(),
+	return &reporter.JobReport{
 		Jobs:     jobs,
-		NewJobs:  newJobs,
-		Title:    *title,
-		Location: *location,
-	}
+		NewJobs:  changes.New,
+		Title:    params.Title,
+		Location: params.Location,
+		Profile:  profile.Name,
 
-	// Send email
-	config := reporter.EmailConfig{
-		SMTPHost:     os.Getenv("SMTP_HOST"),
-		SMTPPort:     587, // Default port for TLS
-		SMTPUsername: os.Getenv("SMTP_USERNAME"),
-		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
-		FromEmail:    os.Getenv("FROM_EMAIL"),
-		ToEmail:      *email,
+		RepostedJobs: changes.Reposted,
+		ClosedJobs:   changes.Closed,
+
+		FailedSources: result.Failed(),
+		HealthAlarms:  alarms,
+	}, nil
+}
+
+// importPreviousJobs moves the jobs from a previous_jobs.txt written by
+// earlier versions into the store, then renames the file so it is only
+// imported once.
+func importPreviousJobs(jobStore *store.Store, path string) {
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	added, err := jobStore.ImportPreviousJobs(context.Background(), path)
+	if err != nil {
+		log.Printf("Warning: Failed to import %s: %v", path, err)
+		return
+	}
+	log.Printf("Imported %d jobs from %s", added, path)
+	if err := os.Rename(path, path+".imported"); err != nil {
+		log.Printf("Warning: Failed to rename %s: %v", path, err)
 	}
+}
 
-	log.Printf("Sending email report to %s", *email)
-	if err := reporter.SendJobReport(config, report); err != nil {
-		log.Fatalf("Failed to send email report: %v", err)
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	log.Printf("Email sent successfully")
+	return ""
 }
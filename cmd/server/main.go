@@ -1,35 +1,110 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 	"job-hunter/internal/api"
 	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
 	"job-hunter/internal/logger"
+	"job-hunter/internal/store"
 )
 
 func main() {
+	dataDir := flag.String("data-dir", "", "Directory to store the job history, source health and job details")
+	concurrency := flag.Int("concurrency", 4, "Maximum number of sources crawled in parallel")
+	sources := flag.String("sources", "", "Comma-separated list of sources to search (default: all registered)")
+	maxPages := flag.Int("max-pages", 5, "Maximum result pages fetched per source")
+	maxJobs := flag.Int("max-jobs", 250, "Maximum jobs collected per source")
+	proxy := flag.String("proxy", "", "HTTP(S) or SOCKS5 proxy URL for all crawler requests")
+	respectRobots := flag.Bool("respect-robots", false, "Skip URLs disallowed by each site's robots.txt and honor its Crawl-delay")
+	robotsContact := flag.String("robots-contact", "", "URL added to the User-Agent with -respect-robots, where site owners can learn about the crawler")
+	cacheDir := flag.String("cache-dir", "", "Directory for cached HTTP responses (default: no cache)")
+	cacheTTL := flag.Duration("cache-ttl", 15*time.Minute, "How long cached responses are reused before revalidating")
+	careersURLs := flag.String("careers-urls", "", "Comma-separated company careers pages publishing JobPosting JSON-LD, read by the Careers source")
+	enrich := flag.Bool("enrich", false, "Read each job's detail page for its full description, salary and posted date")
+	enrichMax := flag.Int("enrich-max", 100, "Maximum detail pages read per search")
+	selectorsDir := flag.String("selectors-dir", "", "Directory of selector spec files overriding the built-in ones")
+	sourceTimeout := flag.Duration("source-timeout", crawler.DefaultConfig().SourceTimeout, "Deadline for each individual source")
+	closeAfter := flag.Int("close-after", 3, "Runs in a row a job must be missing from, while its source succeeds, before it is closed (0: never)")
+	searchRetention := flag.Duration("search-retention", api.DefaultSearchRetention, "How long the results of finished asynchronous searches are kept")
+	flag.Parse()
+
 	// Initialize logger
 	logger.Init()
 
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	
+
 	// Use gin middleware
 	r.Use(gin.Recovery())
 	r.Use(loggerMiddleware())
-	
+
+	if *dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get home directory")
+		}
+		*dataDir = filepath.Join(home, ".job-hunter")
+	}
+
 	// Initialize crawlers
-	jobCrawler := crawler.NewJobCrawler()
-	
+	crawlerConfig := crawler.DefaultConfig()
+	crawlerConfig.Concurrency = *concurrency
+	crawlerConfig.SourceTimeout = *sourceTimeout
+	crawlerConfig.Pagination.MaxPages = *maxPages
+	crawlerConfig.Pagination.MaxJobs = *maxJobs
+	crawlerConfig.HTTP.ProxyURL = *proxy
+	crawlerConfig.HTTP.RespectRobots = *respectRobots
+	crawlerConfig.HTTP.RobotsContact = *robotsContact
+	crawlerConfig.HTTP.Cache.Dir = *cacheDir
+	crawlerConfig.HTTP.Cache.DefaultTTL = *cacheTTL
+	crawlerConfig.SelectorsDir = *selectorsDir
+	crawlerConfig.Enrich.Enabled = *enrich
+	crawlerConfig.Enrich.MaxJobs = *enrichMax
+	crawlerConfig.Enrich.Cache = crawler.NewFileEnrichmentCache(filepath.Join(*dataDir, "enriched_jobs.json"), 30*24*time.Hour)
+	if *careersURLs != "" {
+		crawlerConfig.Sources = map[string]crawler.SourceConfig{
+			"Careers": {URLs: crawler.SplitList(*careersURLs)},
+		}
+	}
+	if *sources != "" {
+		crawlerConfig.EnabledSources = crawler.SplitList(*sources)
+	}
+	jobCrawler, err := crawler.NewJobCrawler(crawlerConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize crawler")
+	}
+
+	monitor := health.NewMonitor(filepath.Join(*dataDir, "source_health.json"), health.DefaultOptions())
+
+	storeOptions := store.DefaultOptions()
+	storeOptions.CloseAfter = *closeAfter
+	jobStore, err := store.Open(filepath.Join(*dataDir, "jobs.db"), storeOptions)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open job store")
+	}
+	defer jobStore.Close()
+
 	// Initialize API handlers
-	handler := api.NewHandler(jobCrawler)
-	
+	handler := api.NewHandler(jobCrawler, monitor, jobStore, *searchRetention)
+
 	// Routes
 	r.GET("/api/jobs", handler.GetJobs)
 	r.GET("/api/jobs/search", handler.SearchJobs)
-	
+	r.GET("/api/jobs/search/stream", handler.StreamSearch)
+	r.POST("/api/searches", handler.StartSearch)
+	r.GET("/api/searches/:id", handler.GetSearch)
+	r.GET("/api/searches/:id/results", handler.GetSearchResults)
+	r.DELETE("/api/searches/:id", handler.CancelSearch)
+	r.GET("/api/health/sources", handler.SourceHealth)
+
 	// Start server
 	log.Info().Msg("Starting server on :8080")
 	if err := r.Run(":8080"); err != nil {
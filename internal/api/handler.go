@@ -2,49 +2,308 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"job-hunter/internal/crawler"
-	"job-hunter/internal/models"
+	"job-hunter/internal/health"
+	"job-hunter/internal/logger"
+	"job-hunter/internal/query"
+	"job-hunter/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
 type JobSearcher interface {
-	SearchJobs(ctx context.Context, params crawler.JobSearchParams) ([]models.Job, error)
+	SearchJobs(ctx context.Context, params crawler.JobSearchParams) (*crawler.SearchResult, error)
 }
 
 type Handler struct {
 	crawler JobSearcher
+	health  *health.Monitor
+	store   *store.Store
+	// searches runs the asynchronous searches.
+	searches *searchManager
 }
 
-func NewHandler(crawler *crawler.JobCrawler) *Handler {
-	return &Handler{crawler: crawler}
+// NewHandler returns the API handlers. monitor may be nil, in which case
+// searches aren't tracked and the health endpoint reports nothing. jobs may
+// be nil, in which case searches aren't recorded, no job is reported as new
+// and finished asynchronous searches are only kept in memory.
+// searchRetention is how long finished asynchronous searches are kept.
+func NewHandler(crawler *crawler.JobCrawler, monitor *health.Monitor, jobs *store.Store, searchRetention time.Duration) *Handler {
+	h := &Handler{crawler: crawler, health: monitor, store: jobs}
+	h.searches = newSearchManager(h, searchRetention)
+	return h
 }
 
+// searchResponse is a search result along with the run it was recorded as.
+type searchResponse struct {
+	*crawler.SearchResult
+	RunID        int64    `json:"run_id,omitempty"`
+	NewJobs      []string `json:"new_jobs,omitempty"`      // IDs of the jobs no earlier run had found
+	RepostedJobs []string `json:"reposted_jobs,omitempty"` // IDs of closed jobs that were found again
+	ClosedJobs   []string `json:"closed_jobs,omitempty"`   // IDs of the jobs this run closed
+}
+
+// search runs a search, feeds its per-source results to the health
+// monitor and records its jobs in the store. A search cut short by ctx
+// isn't recorded: its sources would look broken and its jobs gone.
+func (h *Handler) search(ctx context.Context, params crawler.JobSearchParams) (*searchResponse, error) {
+	startedAt := time.Now()
+	result, err := h.crawler.SearchJobs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	response := &searchResponse{SearchResult: result}
+	if ctx.Err() != nil {
+		return response, nil
+	}
+	log := logger.Get()
+	if h.health != nil {
+		if _, err := h.health.Record(params, result); err != nil {
+			log.Warn().Err(err).Msg("Failed to record source health")
+		}
+	}
+	if h.store != nil {
+		run, changes, err := h.store.RecordRun(ctx, params, startedAt, result)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to record jobs")
+			return response, nil
+		}
+		response.RunID = run.ID
+		for _, job := range changes.New {
+			response.NewJobs = append(response.NewJobs, job.ID)
+		}
+		for _, job := range changes.Reposted {
+			response.RepostedJobs = append(response.RepostedJobs, job.ID)
+		}
+		for _, job := range changes.Closed {
+			response.ClosedJobs = append(response.ClosedJobs, job.ID)
+		}
+	}
+	return response, nil
+}
+
+// maxJobsLimit caps the page size of GetJobs.
+const maxJobsLimit = 200
+
+// GetJobs serves the jobs collected by earlier searches from the store,
+// without searching. See jobFilter for the query parameters.
 func (h *Handler) GetJobs(c *gin.Context) {
-	jobs, err := h.crawler.SearchJobs(c.Request.Context(), crawler.JobSearchParams{})
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "job history is not available"})
+		return
+	}
+	filter, err := jobFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := h.store.Jobs(c.Request.Context(), filter)
+	if errors.Is(err, store.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, jobs)
+	c.JSON(http.StatusOK, page)
 }
 
-func (h *Handler) SearchJobs(c *gin.Context) {
-	title := c.Query("title")
-	location := c.Query("location")
+// jobFilter reads a store.JobFilter from the query parameters: source
+// (repeated or comma-separated), company, location, keyword, status,
+// posted_after, posted_before, first_seen_after and first_seen_before
+// (dates or RFC 3339 times), salary_min, salary_max, salary_currency, sort,
+// cursor and limit.
+func jobFilter(c *gin.Context) (store.JobFilter, error) {
+	filter := store.JobFilter{
+		Company:  c.Query("company"),
+		Location: c.Query("location"),
+		Keyword:  c.Query("keyword"),
+		Status:   c.Query("status"),
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+
+		SalaryCurrency: c.Query("salary_currency"),
+	}
+	filter.Sources = queryList(c, "source")
 
-	if title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'title' is required"})
+	times := map[string]*time.Time{
+		"posted_after":      &filter.PostedAfter,
+		"posted_before":     &filter.PostedBefore,
+		"first_seen_after":  &filter.FirstSeenAfter,
+		"first_seen_before": &filter.FirstSeenBefore,
+	}
+	for name, dest := range times {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, value); err != nil {
+				return store.JobFilter{}, fmt.Errorf("query parameter '%s' must be a date or RFC 3339 time", name)
+			}
+		}
+		*dest = t
+	}
+
+	numbers := map[string]*float64{"salary_min": &filter.SalaryMin, "salary_max": &filter.SalaryMax}
+	for name, dest := range numbers {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 {
+			return store.JobFilter{}, fmt.Errorf("query parameter '%s' must be a positive number", name)
+		}
+		*dest = n
+	}
+
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxJobsLimit {
+			return store.JobFilter{}, fmt.Errorf("query parameter 'limit' must be between 1 and %d", maxJobsLimit)
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+// searchRequest is a search as the API takes it, from the query parameters
+// of SearchJobs and StreamSearch or the JSON body of StartSearch.
+type searchRequest struct {
+	Title          string   `json:"title"`
+	Location       string   `json:"location"`
+	Keywords       []string `json:"keywords"`
+	Exclude        []string `json:"exclude"`
+	Workplace      string   `json:"workplace"`
+	EmploymentType string   `json:"employment_type"`
+	Experience     string   `json:"experience"`
+	PostedWithin   string   `json:"posted_within"` // A duration such as "72h"
+	Radius         int      `json:"radius"`
+	SalaryMin      float64  `json:"salary_min"`
+	Query          string   `json:"query"` // A boolean query in the syntax of package query
+}
+
+// searchQuery reads a search from the query parameters: title, location,
+// keyword and exclude (repeated or comma-separated), workplace,
+// employment_type, experience, posted_within, radius, salary_min and q, a
+// boolean query.
+func searchQuery(c *gin.Context) (crawler.JobSearchParams, error) {
+	req := searchRequest{
+		Title:          c.Query("title"),
+		Location:       c.Query("location"),
+		Keywords:       queryList(c, "keyword"),
+		Exclude:        queryList(c, "exclude"),
+		Workplace:      c.Query("workplace"),
+		EmploymentType: c.Query("employment_type"),
+		Experience:     c.Query("experience"),
+		PostedWithin:   c.Query("posted_within"),
+		Query:          c.Query("q"),
+	}
+	if value := c.Query("radius"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return crawler.JobSearchParams{}, errors.New("query parameter 'radius' must be a positive number of miles")
+		}
+		req.Radius = n
+	}
+	if value := c.Query("salary_min"); value != "" {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 {
+			return crawler.JobSearchParams{}, errors.New("query parameter 'salary_min' must be a positive number")
+		}
+		req.SalaryMin = n
+	}
+	return req.params("query parameter")
+}
+
+// params validates the request, naming its fields as what in errors.
+func (r searchRequest) params(what string) (crawler.JobSearchParams, error) {
+	if r.Title == "" {
+		return crawler.JobSearchParams{}, fmt.Errorf("%s 'title' is required", what)
+	}
+	params := crawler.JobSearchParams{
+		Title:           r.Title,
+		Location:        r.Location,
+		Keywords:        r.Keywords,
+		ExcludeKeywords: r.Exclude,
+		Workplace:       r.Workplace,
+		EmploymentType:  r.EmploymentType,
+		ExperienceLevel: r.Experience,
+		Radius:          r.Radius,
+		MinSalary:       r.SalaryMin,
+	}
+	if r.PostedWithin != "" {
+		d, err := time.ParseDuration(r.PostedWithin)
+		if err != nil {
+			return crawler.JobSearchParams{}, fmt.Errorf("%s 'posted_within' must be a duration such as 72h", what)
+		}
+		params.PostedWithin = d
+	}
+	if r.Query != "" {
+		q, err := query.Parse(r.Query)
+		if err != nil {
+			return crawler.JobSearchParams{}, fmt.Errorf("invalid query: %w", err)
+		}
+		params.Query = q
+	}
+	if err := params.Validate(); err != nil {
+		return crawler.JobSearchParams{}, err
+	}
+	return params, nil
+}
+
+// queryList reads a query parameter that may be repeated or hold a
+// comma-separated list.
+func queryList(c *gin.Context, name string) []string {
+	var list []string
+	for _, value := range c.QueryArray(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// SearchJobs runs a search and returns its jobs. See searchQuery for the
+// query parameters.
+func (h *Handler) SearchJobs(c *gin.Context) {
+	params, err := searchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	jobs, err := h.crawler.SearchJobs(c.Request.Context(), crawler.JobSearchParams{
-		Title:    title,
-		Location: location,
-	})
+	result, err := h.search(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, jobs)
+	c.JSON(http.StatusOK, result)
+}
+
+// SourceHealth reports each source's latest results against its baseline,
+// with any drift alarms.
+func (h *Handler) SourceHealth(c *gin.Context) {
+	var sources []health.SourceHealth
+	if h.health != nil {
+		var err error
+		if sources, err = h.health.Sources(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if sources == nil {
+		sources = []health.SourceHealth{}
+	}
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
 }
@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"testing"
+	"time"
 	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
 	"job-hunter/internal/models"
+	"job-hunter/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,6 +20,12 @@ func TestGetJobs(t *testing.T) {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
 
+	jobStore, err := store.Open(filepath.Join(t.TempDir(), "jobs.db"), store.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jobStore.Close()
+
 	// Create handler with mock crawler
 	mockCrawler := &mockJobCrawler{
 		jobs: []models.Job{
@@ -24,32 +35,54 @@ func TestGetJobs(t *testing.T) {
 				Company: "Test Company",
 				Source:  "Test Source",
 			},
+			{
+				ID:      "2",
+				Title:   "Go Developer",
+				Company: "Acme",
+				Source:  "Test Source",
+			},
 		},
 	}
 
-	handler := &Handler{crawler: mockCrawler}
+	handler := &Handler{crawler: mockCrawler, store: jobStore}
 
 	// Create test router
 	r := gin.New()
 	r.GET("/api/jobs", handler.GetJobs)
+	r.GET("/api/jobs/search", handler.SearchJobs)
 
-	// Create test request
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/jobs", nil)
-	r.ServeHTTP(w, req)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := get("/api/jobs/search?title=test"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
 
 	// Check response
+	w := get("/api/jobs?company=acme&limit=10")
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
-	var response []models.Job
+	var response store.JobPage
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
 	}
 
-	if len(response) != 1 {
-		t.Errorf("Expected 1 job, got %d", len(response))
+	if len(response.Jobs) != 1 || response.Total != 1 || response.Jobs[0].Title != "Go Developer" {
+		t.Errorf("Expected the stored Acme job, got %+v", response)
+	}
+	if mockCrawler.calls != 1 {
+		t.Errorf("Expected listing jobs not to search, got %d searches", mockCrawler.calls)
+	}
+
+	for _, url := range []string{"/api/jobs?limit=0", "/api/jobs?posted_after=yesterday", "/api/jobs?sort=pay", "/api/jobs?salary_min=lots"} {
+		if w := get(url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
 	}
 }
 
@@ -83,6 +116,14 @@ func TestSearchJobs(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
+	var result crawler.SearchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Errorf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Jobs) != 1 || len(result.Sources) != 1 || result.Sources[0].JobCount != 1 {
+		t.Errorf("Expected 1 job from 1 source, got %+v", result)
+	}
+
 	// Test without query parameter
 	w = httptest.NewRecorder()
 	req, _ = http.NewRequest("GET", "/api/jobs/search", nil)
@@ -94,9 +135,136 @@ func TestSearchJobs(t *testing.T) {
 }
 
 type mockJobCrawler struct {
-	jobs []models.Job
+	jobs   []models.Job
+	calls  int
+	params crawler.JobSearchParams // Of the last search
+}
+
+func (m *mockJobCrawler) SearchJobs(ctx context.Context, params crawler.JobSearchParams) (*crawler.SearchResult, error) {
+	m.calls++
+	m.params = params
+	return &crawler.SearchResult{
+		Jobs:    m.jobs,
+		Sources: []crawler.SourceStatus{{Name: "Test Source", JobCount: len(m.jobs)}},
+	}, nil
+}
+
+func TestSearchJobsParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCrawler := &mockJobCrawler{}
+	handler := &Handler{crawler: mockCrawler}
+	r := gin.New()
+	r.GET("/api/jobs/search", handler.SearchJobs)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/api/jobs/search?title=golang&keyword=kubernetes,grpc&keyword=aws&exclude=php&workplace=remote" +
+		"&employment_type=full_time&experience=senior&posted_within=72h&radius=25&salary_min=120000" +
+		"&q=" + url.QueryEscape(`(grpc OR rest) AND NOT title:intern`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	params := mockCrawler.params
+	if len(params.Keywords) != 3 || params.Keywords[2] != "aws" || len(params.ExcludeKeywords) != 1 {
+		t.Errorf("Expected the keywords and exclusions, got %+v", params)
+	}
+	if params.Workplace != crawler.WorkplaceRemote || params.EmploymentType != crawler.EmploymentFullTime || params.ExperienceLevel != crawler.ExperienceSenior {
+		t.Errorf("Expected the workplace, employment type and experience level, got %+v", params)
+	}
+	if params.PostedWithin != 72*time.Hour || params.Radius != 25 || params.MinSalary != 120000 {
+		t.Errorf("Expected the recency, radius and salary, got %+v", params)
+	}
+	if params.Query == nil || params.Query.String() != "(grpc OR rest) AND NOT title:intern" {
+		t.Errorf("Expected the query, got %v", params.Query)
+	}
+
+	for _, url := range []string{
+		"/api/jobs/search?title=golang&workplace=office",
+		"/api/jobs/search?title=golang&posted_within=3d",
+		"/api/jobs/search?title=golang&radius=far",
+		"/api/jobs/search?title=golang&salary_min=-1",
+		"/api/jobs/search?title=golang&q=(rust",
+	} {
+		if w := get(url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestSourceHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCrawler := &mockJobCrawler{jobs: []models.Job{{ID: "1", Title: "Go Developer", Company: "Acme", Source: "Test Source"}}}
+	monitor := health.NewMonitor(filepath.Join(t.TempDir(), "health.json"), health.DefaultOptions())
+	handler := &Handler{crawler: mockCrawler, health: monitor}
+
+	r := gin.New()
+	r.GET("/api/jobs/search", handler.SearchJobs)
+	r.GET("/api/health/sources", handler.SourceHealth)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/jobs/search?title=golang", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/health/sources", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Sources []health.SourceHealth `json:"sources"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Sources) != 1 || response.Sources[0].Source != "Test Source" || !response.Sources[0].Healthy {
+		t.Errorf("Expected the search to be recorded as healthy, got %+v", response.Sources)
+	}
 }
 
-func (m *mockJobCrawler) SearchJobs(ctx context.Context, params crawler.JobSearchParams) ([]models.Job, error) {
-	return m.jobs, nil
+func TestSearchJobsRecordsRuns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jobStore, err := store.Open(filepath.Join(t.TempDir(), "jobs.db"), store.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jobStore.Close()
+	mockCrawler := &mockJobCrawler{jobs: []models.Job{{ID: "test:1", Title: "Go Developer", Company: "Acme", Source: "Test Source"}}}
+	handler := &Handler{crawler: mockCrawler, store: jobStore}
+
+	r := gin.New()
+	r.GET("/api/jobs/search", handler.SearchJobs)
+
+	var responses []searchResponse
+	for range 2 {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/jobs/search?title=golang", nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var response searchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		responses = append(responses, response)
+	}
+
+	if responses[0].RunID == 0 || len(responses[0].NewJobs) != 1 || len(responses[0].Jobs) != 1 {
+		t.Errorf("Expected the first search to report its job as new, got %+v", responses[0])
+	}
+	if responses[1].RunID == responses[0].RunID || len(responses[1].NewJobs) != 0 {
+		t.Errorf("Expected the second search to find nothing new, got %+v", responses[1])
+	}
 }
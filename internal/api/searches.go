@@ -0,0 +1,353 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+	"job-hunter/internal/store"
+)
+
+// States of an asynchronous search.
+const (
+	SearchRunning   = "running"
+	SearchCompleted = "completed"
+	SearchFailed    = "failed"
+	SearchCanceled  = "canceled"
+)
+
+// States of a source within an asynchronous search.
+const (
+	SourcePending = "pending"
+	SourceRunning = "running"
+	SourceDone    = "done"
+	SourceFailed  = "failed"
+)
+
+// DefaultSearchRetention is how long finished asynchronous searches are
+// kept by default.
+const DefaultSearchRetention = 24 * time.Hour
+
+// SourceProgress is where one source of an asynchronous search is at.
+type SourceProgress struct {
+	Name       string             `json:"name"`
+	State      string             `json:"state"`
+	JobCount   int                `json:"job_count"`
+	ErrorClass crawler.ErrorClass `json:"error_class,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// SearchStatus describes an asynchronous search.
+type SearchStatus struct {
+	ID         string           `json:"id"`
+	State      string           `json:"state"`
+	Title      string           `json:"title"`
+	Location   string           `json:"location"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt time.Time        `json:"finished_at,omitzero"`
+	Error      string           `json:"error,omitempty"`
+	JobCount   int              `json:"job_count"` // Jobs found once the search completed
+	Sources    []SourceProgress `json:"sources"`
+}
+
+// asyncSearch is a search running in the background, or one that finished
+// and isn't persisted.
+type asyncSearch struct {
+	mu      sync.Mutex
+	status  SearchStatus
+	results *searchResponse
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func (s *asyncSearch) snapshot() SearchStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Sources = append([]SourceProgress(nil), s.status.Sources...)
+	return status
+}
+
+// source returns the progress entry of the named source, adding it if the
+// search didn't know about it. The caller holds s.mu.
+func (s *asyncSearch) source(name string) *SourceProgress {
+	for i := range s.status.Sources {
+		if s.status.Sources[i].Name == name {
+			return &s.status.Sources[i]
+		}
+	}
+	s.status.Sources = append(s.status.Sources, SourceProgress{Name: name, State: SourcePending})
+	return &s.status.Sources[len(s.status.Sources)-1]
+}
+
+// sourceLister is implemented by searchers that can tell up front which
+// sources a search will crawl, such as *crawler.JobCrawler.
+type sourceLister interface {
+	SourceNames() []string
+}
+
+// searchManager runs searches in the background and keeps them for the
+// retention period once they finish: in the job store when there is one,
+// so they outlive the server, and in memory otherwise.
+type searchManager struct {
+	handler   *Handler
+	retention time.Duration
+
+	mu       sync.Mutex
+	searches map[string]*asyncSearch
+}
+
+func newSearchManager(h *Handler, retention time.Duration) *searchManager {
+	return &searchManager{handler: h, retention: retention, searches: make(map[string]*asyncSearch)}
+}
+
+// start runs a search in the background and returns it.
+func (m *searchManager) start(params crawler.JobSearchParams) *asyncSearch {
+	m.prune()
+
+	search := &asyncSearch{
+		status: SearchStatus{
+			ID:        newSearchID(),
+			State:     SearchRunning,
+			Title:     params.Title,
+			Location:  params.Location,
+			CreatedAt: time.Now(),
+			Sources:   []SourceProgress{},
+		},
+		done: make(chan struct{}),
+	}
+	if lister, ok := m.handler.crawler.(sourceLister); ok {
+		for _, name := range lister.SourceNames() {
+			search.status.Sources = append(search.status.Sources, SourceProgress{Name: name, State: SourcePending})
+		}
+	}
+
+	// The search outlives the request that started it.
+	ctx, cancel := context.WithCancel(context.Background())
+	search.cancel = cancel
+	ctx = crawler.WithSearchTrace(ctx, &crawler.SearchTrace{
+		SourceStarted: func(name string) {
+			search.mu.Lock()
+			defer search.mu.Unlock()
+			search.source(name).State = SourceRunning
+		},
+		SourceDone: func(status crawler.SourceStatus, _ []models.Job) {
+			search.mu.Lock()
+			defer search.mu.Unlock()
+			progress := search.source(status.Name)
+			progress.State, progress.JobCount = SourceDone, status.JobCount
+			if !status.OK() {
+				progress.State, progress.ErrorClass, progress.Error = SourceFailed, status.ErrorClass, status.Error
+			}
+		},
+	})
+
+	m.mu.Lock()
+	m.searches[search.status.ID] = search
+	m.mu.Unlock()
+
+	go m.run(ctx, search, params)
+	return search
+}
+
+func (m *searchManager) run(ctx context.Context, search *asyncSearch, params crawler.JobSearchParams) {
+	defer close(search.done)
+	defer search.cancel()
+
+	results, err := m.handler.search(ctx, params)
+
+	search.mu.Lock()
+	search.status.FinishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		search.status.State = SearchCanceled
+	case err != nil:
+		search.status.State, search.status.Error = SearchFailed, err.Error()
+	default:
+		search.status.State, search.status.JobCount = SearchCompleted, len(results.Jobs)
+		search.results = results
+	}
+	search.mu.Unlock()
+
+	if m.handler.store == nil {
+		return
+	}
+	if err := m.persist(search); err != nil {
+		log := logger.Get()
+		log.Warn().Err(err).Str("search", search.status.ID).Msg("Failed to save search")
+		return
+	}
+	m.mu.Lock()
+	delete(m.searches, search.status.ID)
+	m.mu.Unlock()
+}
+
+// persist saves a finished search in the store.
+func (m *searchManager) persist(search *asyncSearch) error {
+	status := search.snapshot()
+	saved := store.SavedSearch{ID: status.ID, FinishedAt: status.FinishedAt}
+	var err error
+	if saved.State, err = json.Marshal(status); err != nil {
+		return err
+	}
+	if search.results != nil {
+		if saved.Results, err = json.Marshal(search.results); err != nil {
+			return err
+		}
+	}
+	return m.handler.store.SaveSearch(context.Background(), saved)
+}
+
+// errSearchNotFound is returned for unknown and expired searches.
+var errSearchNotFound = errors.New("search not found")
+
+// get returns the status of a search, and its results if it completed.
+func (m *searchManager) get(ctx context.Context, id string) (SearchStatus, json.RawMessage, error) {
+	m.mu.Lock()
+	search, ok := m.searches[id]
+	m.mu.Unlock()
+	if ok {
+		status := search.snapshot()
+		if status.State != SearchCompleted {
+			return status, nil, nil
+		}
+		results, err := json.Marshal(search.results)
+		return status, results, err
+	}
+
+	if m.handler.store == nil {
+		return SearchStatus{}, nil, errSearchNotFound
+	}
+	saved, err := m.handler.store.Search(ctx, id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && time.Since(saved.FinishedAt) > m.retention) {
+		return SearchStatus{}, nil, errSearchNotFound
+	}
+	if err != nil {
+		return SearchStatus{}, nil, err
+	}
+	var status SearchStatus
+	if err := json.Unmarshal(saved.State, &status); err != nil {
+		return SearchStatus{}, nil, err
+	}
+	return status, saved.Results, nil
+}
+
+// cancel stops a running search and waits for it to wind down. It reports
+// false if the search isn't running.
+func (m *searchManager) cancel(ctx context.Context, id string) (SearchStatus, bool) {
+	m.mu.Lock()
+	search, ok := m.searches[id]
+	m.mu.Unlock()
+	if !ok || search.snapshot().State != SearchRunning {
+		return SearchStatus{}, false
+	}
+	search.cancel()
+	select {
+	case <-search.done:
+	case <-ctx.Done():
+	}
+	return search.snapshot(), true
+}
+
+// prune drops the searches that finished more than the retention period
+// ago.
+func (m *searchManager) prune() {
+	cutoff := time.Now().Add(-m.retention)
+	m.mu.Lock()
+	for id, search := range m.searches {
+		status := search.snapshot()
+		if status.State != SearchRunning && status.FinishedAt.Before(cutoff) {
+			delete(m.searches, id)
+		}
+	}
+	m.mu.Unlock()
+
+	if m.handler.store != nil {
+		if _, err := m.handler.store.DeleteSearches(context.Background(), cutoff); err != nil {
+			log := logger.Get()
+			log.Warn().Err(err).Msg("Failed to delete expired searches")
+		}
+	}
+}
+
+func newSearchID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// StartSearch starts a search in the background from a JSON body with the
+// fields of searchRequest, and returns its status with the ID to poll.
+func (h *Handler) StartSearch(c *gin.Context) {
+	var body searchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	params, err := body.params("field")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	search := h.searches.start(params)
+	status := search.snapshot()
+	c.Header("Location", "/api/searches/"+status.ID)
+	c.JSON(http.StatusAccepted, status)
+}
+
+// GetSearch reports the progress of a search, source by source.
+func (h *Handler) GetSearch(c *gin.Context) {
+	status, _, err := h.searches.get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// GetSearchResults returns the jobs of a completed search, in the same
+// form as SearchJobs.
+func (h *Handler) GetSearchResults(c *gin.Context) {
+	status, results, err := h.searches.get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	if status.State != SearchCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "search is " + status.State, "search": status})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", results)
+}
+
+// CancelSearch stops a running search.
+func (h *Handler) CancelSearch(c *gin.Context) {
+	status, ok := h.searches.cancel(c.Request.Context(), c.Param("id"))
+	if !ok {
+		if _, _, err := h.searches.get(c.Request.Context(), c.Param("id")); err != nil {
+			searchError(c, err)
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "search is not running"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func searchError(c *gin.Context, err error) {
+	if errors.Is(err, errSearchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+	"job-hunter/internal/store"
+)
+
+// blockingSearcher is a one-source searcher whose source runs until it is
+// released or its search is canceled.
+type blockingSearcher struct {
+	jobs    []models.Job
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingSearcher(jobs ...models.Job) *blockingSearcher {
+	return &blockingSearcher{jobs: jobs, started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (b *blockingSearcher) SourceNames() []string {
+	return []string{"Test Source"}
+}
+
+func (b *blockingSearcher) SearchJobs(ctx context.Context, params crawler.JobSearchParams) (*crawler.SearchResult, error) {
+	trace := crawler.ContextSearchTrace(ctx)
+	trace.SourceStarted("Test Source")
+	b.started <- struct{}{}
+
+	status := crawler.SourceStatus{Name: "Test Source"}
+	var jobs []models.Job
+	select {
+	case <-b.release:
+		jobs = b.jobs
+		status.JobCount = len(jobs)
+	case <-ctx.Done():
+		status.ErrorClass, status.Error = crawler.ErrorClassCanceled, ctx.Err().Error()
+	}
+	trace.SourceDone(status, jobs)
+	return &crawler.SearchResult{Jobs: jobs, Sources: []crawler.SourceStatus{status}}, nil
+}
+
+func newSearchRouter(t *testing.T, searcher JobSearcher, retention time.Duration) (*gin.Engine, *store.Store) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	jobStore, err := store.Open(filepath.Join(t.TempDir(), "jobs.db"), store.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jobStore.Close() })
+
+	handler := &Handler{crawler: searcher, store: jobStore}
+	handler.searches = newSearchManager(handler, retention)
+	r := gin.New()
+	r.POST("/api/searches", handler.StartSearch)
+	r.GET("/api/searches/:id", handler.GetSearch)
+	r.GET("/api/searches/:id/results", handler.GetSearchResults)
+	r.DELETE("/api/searches/:id", handler.CancelSearch)
+	return r, jobStore
+}
+
+func serve(r *gin.Engine, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// waitForState polls a search until it reaches state.
+func waitForState(t *testing.T, r *gin.Engine, id, state string) SearchStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := serve(r, "GET", "/api/searches/"+id, "")
+		var status SearchStatus
+		json.Unmarshal(w.Body.Bytes(), &status)
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected search %s to be %s, got %d %s", id, state, w.Code, w.Body.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAsyncSearch(t *testing.T) {
+	searcher := newBlockingSearcher(models.Job{ID: "test:1", Title: "Go Developer", Company: "Acme", Source: "Test Source"})
+	r, jobStore := newSearchRouter(t, searcher, time.Hour)
+
+	if w := serve(r, "POST", "/api/searches", `{"location": "Remote"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a title, got %d", w.Code)
+	}
+	if w := serve(r, "POST", "/api/searches", `{"title": "golang", "workplace": "office"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown workplace, got %d", w.Code)
+	}
+
+	w := serve(r, "POST", "/api/searches", `{"title": "golang", "location": "Remote"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var started SearchStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatal(err)
+	}
+	if started.ID == "" || w.Header().Get("Location") != "/api/searches/"+started.ID {
+		t.Fatalf("Expected an ID and its location, got %+v", started)
+	}
+	if len(started.Sources) != 1 || started.Sources[0].Name != "Test Source" {
+		t.Errorf("Expected the source to be listed up front, got %+v", started.Sources)
+	}
+
+	<-searcher.started
+	status := waitForState(t, r, started.ID, SearchRunning)
+	if status.Sources[0].State != SourceRunning {
+		t.Errorf("Expected the source to be running, got %+v", status.Sources)
+	}
+	if w := serve(r, "GET", "/api/searches/"+started.ID+"/results", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for results while running, got %d", w.Code)
+	}
+
+	close(searcher.release)
+	status = waitForState(t, r, started.ID, SearchCompleted)
+	if status.JobCount != 1 || status.Sources[0].State != SourceDone || status.Sources[0].JobCount != 1 || status.FinishedAt.IsZero() {
+		t.Errorf("Expected the completed search to report its jobs, got %+v", status)
+	}
+
+	w = serve(r, "GET", "/api/searches/"+started.ID+"/results", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var results searchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Jobs) != 1 || results.RunID == 0 || len(results.NewJobs) != 1 {
+		t.Errorf("Expected the results to be recorded like a search, got %+v", results)
+	}
+
+	// Finished searches are kept in the store and outlive the server.
+	if _, err := jobStore.Search(context.Background(), started.ID); err != nil {
+		t.Errorf("Expected the search to be saved, got %v", err)
+	}
+	if w := serve(r, "DELETE", "/api/searches/"+started.ID, ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 canceling a finished search, got %d", w.Code)
+	}
+	if w := serve(r, "GET", "/api/searches/nope", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown search, got %d", w.Code)
+	}
+}
+
+func TestCancelSearch(t *testing.T) {
+	searcher := newBlockingSearcher(models.Job{ID: "test:1", Title: "Go Developer", Source: "Test Source"})
+	r, jobStore := newSearchRouter(t, searcher, time.Hour)
+
+	var started SearchStatus
+	json.Unmarshal(serve(r, "POST", "/api/searches", `{"title": "golang"}`).Body.Bytes(), &started)
+	<-searcher.started
+
+	w := serve(r, "DELETE", "/api/searches/"+started.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var status SearchStatus
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status.State != SearchCanceled || status.Sources[0].State != SourceFailed {
+		t.Errorf("Expected the search to be canceled, got %+v", status)
+	}
+	if w := serve(r, "GET", "/api/searches/"+started.ID+"/results", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for the results of a canceled search, got %d", w.Code)
+	}
+
+	// A canceled search isn't recorded as a run.
+	if runs, err := jobStore.Runs(context.Background(), 0); err != nil || len(runs) != 0 {
+		t.Errorf("Expected no recorded runs, got %+v, %v", runs, err)
+	}
+}
+
+func TestSearchRetention(t *testing.T) {
+	searcher := newBlockingSearcher()
+	close(searcher.release)
+	r, _ := newSearchRouter(t, searcher, time.Millisecond)
+
+	var started SearchStatus
+	json.Unmarshal(serve(r, "POST", "/api/searches", `{"title": "golang"}`).Body.Bytes(), &started)
+	<-searcher.started
+
+	deadline := time.Now().Add(5 * time.Second)
+	for serve(r, "GET", "/api/searches/"+started.ID, "").Code != http.StatusNotFound {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the search to expire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+// Server-Sent Events of StreamSearch.
+const (
+	// EventSourceStarted carries {"source": name} when a source starts.
+	EventSourceStarted = "source_started"
+	// EventJobs carries the jobs of a source that finished, before they
+	// are merged with other sources' listings.
+	EventJobs = "jobs"
+	// EventSourceError carries the crawler.SourceStatus of a source that
+	// failed.
+	EventSourceError = "source_error"
+	// EventSummary ends a stream that completed. It carries the same
+	// response as SearchJobs, with the merged jobs.
+	EventSummary = "summary"
+	// EventError ends a stream whose search failed.
+	EventError = "error"
+)
+
+// sourceJobs is the data of an EventJobs event.
+type sourceJobs struct {
+	Source   string       `json:"source"`
+	JobCount int          `json:"job_count"`
+	Jobs     []models.Job `json:"jobs"`
+}
+
+type streamEvent struct {
+	name string
+	data any
+}
+
+// StreamSearch runs a search like SearchJobs, but streams its progress as
+// Server-Sent Events: each source's start, its jobs or its error as it
+// finishes, and a summary at the end. A client that disconnects cancels
+// the search.
+func (h *Handler) StreamSearch(c *gin.Context) {
+	params, err := searchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	events := make(chan streamEvent)
+	send := func(name string, data any) {
+		select {
+		case events <- streamEvent{name, data}:
+		case <-ctx.Done():
+		}
+	}
+	ctx = crawler.WithSearchTrace(ctx, &crawler.SearchTrace{
+		SourceStarted: func(source string) {
+			send(EventSourceStarted, gin.H{"source": source})
+		},
+		SourceDone: func(status crawler.SourceStatus, jobs []models.Job) {
+			if !status.OK() {
+				send(EventSourceError, status)
+				return
+			}
+			if jobs == nil {
+				jobs = []models.Job{}
+			}
+			send(EventJobs, sourceJobs{Source: status.Name, JobCount: len(jobs), Jobs: jobs})
+		},
+	})
+
+	var response *searchResponse
+	go func() {
+		response, err = h.search(ctx, params)
+		close(events)
+	}()
+
+	// Keep proxies such as nginx from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if ok {
+			c.SSEvent(event.name, event.data)
+			return true
+		}
+		if err != nil {
+			c.SSEvent(EventError, gin.H{"error": err.Error()})
+		} else {
+			c.SSEvent(EventSummary, response)
+		}
+		return false
+	})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+// tracingSearcher calls the trace hooks for each of its sources in turn.
+type tracingSearcher struct {
+	sources []crawler.SourceStatus
+	jobs    map[string][]models.Job
+}
+
+func (s *tracingSearcher) SearchJobs(ctx context.Context, params crawler.JobSearchParams) (*crawler.SearchResult, error) {
+	trace := crawler.ContextSearchTrace(ctx)
+	result := &crawler.SearchResult{Sources: s.sources}
+	for _, status := range s.sources {
+		trace.SourceStarted(status.Name)
+		trace.SourceDone(status, s.jobs[status.Name])
+		result.Jobs = append(result.Jobs, s.jobs[status.Name]...)
+	}
+	return result, nil
+}
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads a Server-Sent Events stream to its end.
+func readEvents(t *testing.T, url string) []sseEvent {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, ct)
+	}
+
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			event.data += strings.TrimPrefix(line, "data:")
+		case line == "" && event.name != "":
+			events = append(events, event)
+			event = sseEvent{}
+		}
+	}
+	return events
+}
+
+func TestStreamSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	searcher := &tracingSearcher{
+		sources: []crawler.SourceStatus{
+			{Name: "LinkedIn", JobCount: 1},
+			{Name: "Glassdoor", ErrorClass: crawler.ErrorClassHTTPStatus, StatusCode: 403, Blocked: true, Error: "HTTP 403"},
+		},
+		jobs: map[string][]models.Job{
+			"LinkedIn": {{ID: "linkedin:1", Title: "Go Developer", Company: "Acme", Source: "LinkedIn"}},
+		},
+	}
+	handler := &Handler{crawler: searcher}
+	r := gin.New()
+	r.GET("/api/jobs/search/stream", handler.StreamSearch)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	events := readEvents(t, server.URL+"/api/jobs/search/stream?title=golang")
+	var names []string
+	for _, e := range events {
+		names = append(names, e.name)
+	}
+	want := []string{EventSourceStarted, EventJobs, EventSourceStarted, EventSourceError, EventSummary}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected events %v, got %v", want, names)
+	}
+
+	var batch sourceJobs
+	if err := json.Unmarshal([]byte(events[1].data), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Source != "LinkedIn" || batch.JobCount != 1 || batch.Jobs[0].ID != "linkedin:1" {
+		t.Errorf("Expected LinkedIn's jobs, got %+v", batch)
+	}
+	var failed crawler.SourceStatus
+	if err := json.Unmarshal([]byte(events[3].data), &failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed.Name != "Glassdoor" || failed.StatusCode != 403 || !failed.Blocked {
+		t.Errorf("Expected Glassdoor's error, got %+v", failed)
+	}
+	var summary searchResponse
+	if err := json.Unmarshal([]byte(events[4].data), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Jobs) != 1 || len(summary.Sources) != 2 {
+		t.Errorf("Expected the summary to hold the whole result, got %+v", summary)
+	}
+
+	resp, err := http.Get(server.URL + "/api/jobs/search/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a title, got %d", resp.StatusCode)
+	}
+}
+
+type failingSearcher struct{}
+
+func (failingSearcher) SearchJobs(ctx context.Context, params crawler.JobSearchParams) (*crawler.SearchResult, error) {
+	return nil, errors.New("no sources enabled")
+}
+
+func TestStreamSearchError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/jobs/search/stream", (&Handler{crawler: failingSearcher{}}).StreamSearch)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	events := readEvents(t, server.URL+"/api/jobs/search/stream?title=golang")
+	if len(events) != 1 || events[0].name != EventError || !strings.Contains(events[0].data, "no sources enabled") {
+		t.Errorf("Expected a single error event, got %+v", events)
+	}
+}
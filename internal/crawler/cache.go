@@ -0,0 +1,200 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"job-hunter/internal/logger"
+)
+
+// CacheConfig configures the on-disk HTTP response cache.
+type CacheConfig struct {
+	// Dir is where cached responses are stored. Empty disables the cache.
+	Dir string
+	// DefaultTTL is how long a response is served without asking the site
+	// again. Zero or negative disables caching for sources without an
+	// entry in SourceTTLs.
+	DefaultTTL time.Duration
+	// SourceTTLs overrides DefaultTTL per source name.
+	SourceTTLs map[string]time.Duration
+}
+
+func (c CacheConfig) ttl(source string) time.Duration {
+	for name, ttl := range c.SourceTTLs {
+		if strings.EqualFold(name, source) {
+			return ttl
+		}
+	}
+	return c.DefaultTTL
+}
+
+// cacheKeyHeaders are the request headers that change what a site sends
+// back, and so are part of the cache key. The User-Agent is left out on
+// purpose because it rotates on every request.
+var cacheKeyHeaders = []string{"Accept", "Accept-Language"}
+
+// cacheEntry is the on-disk form of a cached response.
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// cacheTransport serves GET requests from a content-addressed disk cache.
+// Fresh entries are returned without touching the network; stale entries
+// with an ETag or Last-Modified are revalidated with a conditional request.
+// Only 200 responses are stored. TTLs come from our config rather than the
+// sites' Cache-Control headers, which job boards set to no-store anyway.
+type cacheTransport struct {
+	base   http.RoundTripper
+	config CacheConfig
+}
+
+func newCacheTransport(base http.RoundTripper, config CacheConfig) (*cacheTransport, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &cacheTransport{base: base, config: config}, nil
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	source := sourceFromContext(req.Context())
+	ttl := t.config.ttl(source)
+	if req.Method != http.MethodGet || ttl <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	log := logger.Get().With().Str("source", source).Str("url", req.URL.String()).Logger()
+
+	path := t.path(req)
+	entry, err := readCacheEntry(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Msg("Ignoring unreadable cache entry")
+	}
+	if entry != nil && time.Since(entry.StoredAt) < ttl {
+		log.Debug().Msg("Serving response from cache")
+		return entry.response(req), nil
+	}
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := entry.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		log.Debug().Msg("Cached response revalidated")
+		entry.StoredAt = time.Now()
+		if err := writeCacheEntry(path, entry); err != nil {
+			log.Warn().Err(err).Msg("Failed to refresh cache entry")
+		}
+		return entry.response(req), nil
+
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		entry = &cacheEntry{
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       body,
+			StoredAt:   time.Now(),
+		}
+		if err := writeCacheEntry(path, entry); err != nil {
+			log.Warn().Err(err).Msg("Failed to write cache entry")
+		}
+	}
+	return resp, nil
+}
+
+// path returns where the response to req is cached: the SHA-256 of the
+// method, URL and key headers, fanned out over 256 directories.
+func (t *cacheTransport) path(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	for _, k := range cacheKeyHeaders {
+		fmt.Fprintf(h, "%s: %s\n", k, req.Header.Get(k))
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(t.config.Dir, sum[:2], sum+".json")
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-Cache", "HIT")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// writeCacheEntry writes through a temp file so readers never see a
+// half-written entry.
+func writeCacheEntry(path string, e *cacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var calls, revalidated int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("jobs for " + r.URL.Query().Get("q")))
+	}))
+	defer server.Close()
+
+	config := CacheConfig{
+		Dir:        t.TempDir(),
+		DefaultTTL: time.Hour,
+		SourceTTLs: map[string]time.Duration{"Stale": time.Nanosecond},
+	}
+	transport, err := newCacheTransport(http.DefaultTransport, config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	get := func(source, query string) string {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(withSource(t.Context(), source), "GET", server.URL+"?q="+query, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// A fresh entry is served without asking the server again.
+	get("Fresh", "go")
+	if body := get("Fresh", "go"); body != "jobs for go" {
+		t.Errorf("Expected cached body, got %q", body)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 request for a fresh entry, got %d", calls)
+	}
+
+	// A different URL is a different entry.
+	if body := get("Fresh", "rust"); body != "jobs for rust" {
+		t.Errorf("Expected body for the new query, got %q", body)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 requests, got %d", calls)
+	}
+
+	// A stale entry is revalidated and the 304 served from the cache.
+	get("Stale", "java")
+	if body := get("Stale", "java"); body != "jobs for java" {
+		t.Errorf("Expected cached body after revalidation, got %q", body)
+	}
+	if calls != 4 || revalidated != 1 {
+		t.Errorf("Expected 4 requests with 1 revalidation, got %d and %d", calls, revalidated)
+	}
+}
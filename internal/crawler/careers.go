@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+)
+
+func init() {
+	Register("Careers", func(opts SourceOptions) Source {
+		if len(opts.Config.URLs) == 0 {
+			return nil
+		}
+		return NewCareersCrawler(opts)
+	})
+}
+
+// CareersCrawler reads company careers pages that publish their openings as
+// schema.org JobPosting JSON-LD, which most applicant tracking systems do.
+// The pages come from the source's configured URLs; since they can't be
+// searched, postings are matched against the search here.
+type CareersCrawler struct {
+	client *http.Client
+	config SourceConfig
+}
+
+func NewCareersCrawler(opts SourceOptions) *CareersCrawler {
+	return &CareersCrawler{
+		client: opts.client("Careers"),
+		config: opts.Config,
+	}
+}
+
+func (c *CareersCrawler) Name() string {
+	return "Careers"
+}
+
+func (c *CareersCrawler) Capabilities() Capabilities {
+	return Capabilities{Location: true, Pagination: false}
+}
+
+// Crawl reads every configured page. A page that fails is logged and
+// skipped; the crawl only fails when no page could be read.
+func (c *CareersCrawler) Crawl(ctx context.Context, params JobSearchParams) ([]models.Job, error) {
+	log := logger.Get().With().Str("source", "Careers").Logger()
+
+	var (
+		jobs     []models.Job
+		seen     = make(map[string]bool)
+		firstErr error
+		fetched  int
+	)
+	for _, pageURL := range c.config.URLs {
+		body, err := getPage(ctx, c.client, c.Name(), pageURL, nil)
+		if err == nil {
+			var found []models.Job
+			if found, err = ExtractJSONLD(body, c.Name(), pageURL); err == nil {
+				fetched++
+				log.Debug().Str("url", pageURL).Int("job_count", len(found)).Msg("Read careers page")
+				for _, job := range found {
+					if !seen[job.ID] && matchesSearch(job, params) {
+						seen[job.ID] = true
+						jobs = append(jobs, job)
+					}
+				}
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Warn().Err(err).Str("url", pageURL).Msg("Failed to read careers page")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if fetched == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	log.Info().Int("job_count", len(jobs)).Msg("Completed careers crawl")
+	return jobs, nil
+}
+
+// matchesSearch reports whether every word of the searched title appears in
+// the job title, and the searched location in the job location.
+func matchesSearch(job models.Job, params JobSearchParams) bool {
+	title := strings.ToLower(job.Title)
+	for _, word := range strings.Fields(strings.ToLower(params.Title)) {
+		if !strings.Contains(title, word) {
+			return false
+		}
+	}
+	if loc := strings.ToLower(strings.TrimSpace(params.Location)); loc != "" {
+		if !strings.Contains(strings.ToLower(job.Location), loc) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/time/rate"
+	"job-hunter/internal/logger"
+)
+
+// HTTPConfig configures the HTTP clients handed to the crawlers.
+type HTTPConfig struct {
+	// Timeout bounds a single request, retries included. Backoffs that
+	// would outlast it aren't waited out; the last response is returned.
+	Timeout time.Duration
+	// DefaultRate is the request rate per second allowed for any host
+	// without an entry in HostRates. Zero means unlimited.
+	DefaultRate float64
+	// HostRates overrides DefaultRate per host, e.g. "www.glassdoor.com": 0.5.
+	HostRates map[string]float64
+	// UserAgents is the pool of User-Agent strings rotated across requests.
+	UserAgents []string
+	// ProxyURL routes all requests through an HTTP(S) or SOCKS5 proxy,
+	// e.g. "socks5://127.0.0.1:1080".
+	ProxyURL string
+	// Retry is the retry and circuit-breaker policy.
+	Retry RetryPolicy
+	// RespectRobots makes every request obey the target site's robots.txt:
+	// disallowed URLs fail with a *RobotsBlockedError and Crawl-delay is
+	// honoured. Requests then identify the crawler by RobotsAgent in
+	// their User-Agent instead of rotating UserAgents.
+	RespectRobots bool
+	// RobotsAgent is the product token looked up in robots.txt files.
+	// Defaults to DefaultRobotsAgent.
+	RobotsAgent string
+	// RobotsContact is a URL added to the User-Agent while RespectRobots
+	// is on, where site owners can learn about the crawler or reach
+	// whoever runs it.
+	RobotsContact string
+	// Cache keeps responses on disk so repeated searches don't hit the
+	// sites again. It is off unless Cache.Dir is set.
+	Cache CacheConfig
+	// Transport replaces the network transport at the bottom of the stack.
+	// Tests use it to swap in a fake; ProxyURL is ignored when it is set.
+	Transport http.RoundTripper
+}
+
+// DefaultHTTPConfig returns the settings used by the CLI and the API server.
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:     20 * time.Second,
+		DefaultRate: 1,
+		HostRates: map[string]float64{
+			"www.glassdoor.com": 0.5,
+		},
+		UserAgents: []string{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+		},
+		Retry: DefaultRetryPolicy(),
+		Cache: CacheConfig{DefaultTTL: 15 * time.Minute},
+	}
+}
+
+// defaultHeaders are sent with every request unless the crawler sets its own.
+var defaultHeaders = map[string]string{
+	"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+	"Accept-Language":           "en-US,en;q=0.5",
+	"Upgrade-Insecure-Requests": "1",
+}
+
+// ClientFactory hands out HTTP clients that share one transport stack:
+// browser-like headers, a rotating User-Agent, a cookie jar, optional
+// robots.txt compliance (which swaps the rotating User-Agent for one naming
+// the crawler), an optional disk cache, retries with a per-host circuit
+// breaker, per-host rate limits and an optional proxy. Sharing the stack
+// means limits hold across sources and searches.
+type ClientFactory struct {
+	config    HTTPConfig
+	jar       http.CookieJar
+	transport http.RoundTripper
+	agent     atomic.Uint64
+	identity  string // User-Agent sent on every request while obeying robots.txt
+}
+
+// NewClientFactory builds the shared transport stack described by config.
+func NewClientFactory(config HTTPConfig) (*ClientFactory, error) {
+	base := config.Transport
+	if base == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if config.ProxyURL != "" {
+			proxy, err := url.Parse(config.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("parsing proxy URL: %w", err)
+			}
+			switch proxy.Scheme {
+			case "http", "https", "socks5", "socks5h":
+			default:
+				return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+			}
+			t.Proxy = http.ProxyURL(proxy)
+		}
+		base = t
+	}
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %w", err)
+	}
+
+	limited := &rateLimitTransport{
+		base:        base,
+		defaultRate: config.DefaultRate,
+		rates:       config.HostRates,
+		limiters:    make(map[string]*rate.Limiter),
+	}
+	var transport http.RoundTripper = NewRetryTransport(limited, config.Retry)
+	if config.Cache.Dir != "" {
+		transport, err = newCacheTransport(transport, config.Cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var identity string
+	if config.RespectRobots {
+		identity = robotsUserAgent(config.RobotsAgent, config.RobotsContact)
+		transport = newRobotsTransport(transport, config.RobotsAgent, identity)
+	}
+	return &ClientFactory{
+		config:    config,
+		jar:       jar,
+		transport: transport,
+		identity:  identity,
+	}, nil
+}
+
+var (
+	defaultClientsOnce sync.Once
+	defaultClients     *ClientFactory
+)
+
+// defaultClientFactory is used by sources built without an explicit client.
+func defaultClientFactory() *ClientFactory {
+	defaultClientsOnce.Do(func() {
+		var err error
+		defaultClients, err = NewClientFactory(DefaultHTTPConfig())
+		if err != nil {
+			panic(err) // the default config has no proxy to get wrong
+		}
+	})
+	return defaultClients
+}
+
+// Client returns an HTTP client for the named source.
+func (f *ClientFactory) Client(source string) *http.Client {
+	return &http.Client{
+		Timeout:   f.config.Timeout,
+		Jar:       f.jar,
+		Transport: &headerTransport{base: f.transport, source: source, factory: f},
+	}
+}
+
+func (f *ClientFactory) userAgent() string {
+	if len(f.config.UserAgents) == 0 {
+		return ""
+	}
+	n := f.agent.Add(1) - 1
+	return f.config.UserAgents[n%uint64(len(f.config.UserAgents))]
+}
+
+type sourceKey struct{}
+
+// withSource records which source a request belongs to, for the transports
+// further down the stack.
+func withSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// sourceFromContext returns the source recorded by withSource, if any.
+func sourceFromContext(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+// headerTransport fills in the default headers and the User-Agent, and tags
+// the request with its source.
+type headerTransport struct {
+	base    http.RoundTripper
+	source  string
+	factory *ClientFactory
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(withSource(req.Context(), t.source))
+	for k, v := range defaultHeaders {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	// While obeying robots.txt, every request names the agent whose rules
+	// it follows.
+	if t.factory.identity != "" {
+		req.Header.Set("User-Agent", t.factory.identity)
+	} else if req.Header.Get("User-Agent") == "" {
+		if ua := t.factory.userAgent(); ua != "" {
+			req.Header.Set("User-Agent", ua)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// rateLimitTransport spaces out requests per host.
+type rateLimitTransport struct {
+	base        http.RoundTripper
+	defaultRate float64
+	rates       map[string]float64
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func (t *rateLimitTransport) limiter(host string) *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[host]
+	if !ok {
+		r, found := t.rates[host]
+		if !found {
+			r = t.defaultRate
+		}
+		limit := rate.Inf
+		if r > 0 {
+			limit = rate.Limit(r)
+		}
+		l = rate.NewLimiter(limit, 1)
+		t.limiters[host] = l
+	}
+	return l
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter(req.URL.Host).Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// getPage sends a GET request for pageURL and returns the response body.
+// Non-200 responses are reported as a *StatusError.
+func getPage(ctx context.Context, client *http.Client, source, pageURL string, header http.Header) ([]byte, error) {
+	log := logger.Get().With().Str("source", source).Logger()
+
+	log.Info().Str("url", pageURL).Msg("Creating request")
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create request")
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	log.Debug().Msg("Sending request")
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send request")
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Error().Int("status_code", resp.StatusCode).Msg("Unexpected status code")
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	log.Debug().Int("status_code", resp.StatusCode).Msg("Request successful")
+
+	// Read all response bytes
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read response body")
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	return body, nil
+}
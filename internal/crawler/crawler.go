@@ -2,109 +2,382 @@ package crawler
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"job-hunter/internal/models"
+	"job-hunter/internal/query"
 )
 
 type JobCrawler struct {
+	config  Config
 	sources []Source
+	// enricher reads the detail pages of the jobs found, when enabled.
+	enricher *Enricher
+
+	gatesMu sync.Mutex
+	gates   map[Source]*politeGate
 }
 
+// JobSearchParams describes a search. Sources translate what they can into
+// their own query parameters; FilterJobs enforces the rest on the jobs
+// they return. Zero fields don't restrict the search.
 type JobSearchParams struct {
 	Title    string
 	Location string
+	// Keywords must all appear in a job's title, company or description.
+	Keywords []string
+	// ExcludeKeywords must not appear in a job's title, company or
+	// description.
+	ExcludeKeywords []string
+	// Workplace is one of WorkplaceRemote, WorkplaceHybrid and
+	// WorkplaceOnsite.
+	Workplace string
+	// EmploymentType is one of EmploymentTypes, e.g. "full_time".
+	EmploymentType string
+	// ExperienceLevel is one of ExperienceLevels, e.g. "senior".
+	ExperienceLevel string
+	// PostedWithin drops jobs posted longer ago than this.
+	PostedWithin time.Duration
+	// Radius is how far from Location to search, in miles. It is only
+	// applied by sources that support it.
+	Radius int
+	// MinSalary drops jobs whose yearly salary is known to be lower.
+	MinSalary float64
+	// Query is a boolean query every job must match. Its simple terms
+	// are added to the sites' searches like Keywords and ExcludeKeywords.
+	Query *query.Query
 }
 
+// Source is a job site the crawler can search. New sources make themselves
+// available through Register.
 type Source interface {
+	// Name is the human-readable name used in logs, reports and config.
+	Name() string
+	Capabilities() Capabilities
 	Crawl(ctx context.Context, params JobSearchParams) ([]models.Job, error)
 }
 
-func NewJobCrawler() *JobCrawler {
-	return &JobCrawler{
-		sources: []Source{
-			NewLinkedInCrawler(),
-			NewIndeedCrawler(),
-			NewMonsterCrawler(),
-			NewGlassdoorCrawler(),
+// Config controls how a JobCrawler schedules its sources.
+type Config struct {
+	// Concurrency caps how many sources are crawled at the same time.
+	// Zero means every source runs in parallel.
+	Concurrency int
+	// SourceTimeout is the default deadline for a single source. Zero means
+	// the source is only bounded by the caller's context.
+	SourceTimeout time.Duration
+	// PolitenessDelay is the default minimum gap between two crawls of the
+	// same source, so back-to-back searches don't hammer one site.
+	PolitenessDelay time.Duration
+	// EnabledSources restricts the crawler to the named sources, in the
+	// given order. Empty means every registered source.
+	EnabledSources []string
+	// DisabledSources removes the named sources from the enabled set.
+	DisabledSources []string
+	// Pagination is the default paging limit for every source.
+	Pagination Pagination
+	// Sources holds per-source overrides keyed by source name.
+	Sources map[string]SourceConfig
+	// HTTP configures the clients handed to the sources.
+	HTTP HTTPConfig
+	// Clients, when set, is used instead of a factory built from HTTP. It
+	// lets several crawlers share rate limits and circuit breakers.
+	Clients *ClientFactory
+	// SelectorsDir holds selector spec files that replace the built-in ones
+	// for their sources. Empty means only the built-in specs are used.
+	SelectorsDir string
+	// Dedupe controls how listings of one job on several sources are
+	// merged into a single job.
+	Dedupe DedupeConfig
+	// Enrich configures the detail-page pass run after the sources.
+	Enrich EnrichConfig
+}
+
+// SourceConfig overrides the crawler-wide defaults for a single source.
+type SourceConfig struct {
+	Timeout time.Duration
+	// Delay is the politeness delay, applied between crawls of the source
+	// and between the pages of a single crawl.
+	Delay      time.Duration
+	Pagination Pagination
+	// URLs are the pages read by sources that crawl fixed pages rather
+	// than a search, such as Careers.
+	URLs []string
+}
+
+// DefaultConfig returns the settings used by the CLI and the API server.
+func DefaultConfig() Config {
+	return Config{
+		Concurrency:     4,
+		SourceTimeout:   45 * time.Second,
+		PolitenessDelay: 2 * time.Second,
+		Pagination: Pagination{
+			MaxPages: 5,
+			MaxJobs:  250,
+			MaxAge:   14 * 24 * time.Hour,
 		},
+		HTTP:   DefaultHTTPConfig(),
+		Dedupe: DefaultDedupeConfig(),
+		Enrich: EnrichConfig{Concurrency: 4, MaxJobs: 100},
+	}
+}
+
+// sourceConfig resolves the effective settings for the named source.
+func (c Config) sourceConfig(name string) SourceConfig {
+	sc := SourceConfig{Timeout: c.SourceTimeout, Delay: c.PolitenessDelay, Pagination: c.Pagination}
+	for key, o := range c.Sources {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		if o.Timeout > 0 {
+			sc.Timeout = o.Timeout
+		}
+		if o.Delay > 0 {
+			sc.Delay = o.Delay
+		}
+		sc.Pagination = o.Pagination.merge(sc.Pagination)
+		sc.URLs = o.URLs
+	}
+	return sc
+}
+
+// NewJobCrawler builds a crawler from the registered sources enabled in config.
+func NewJobCrawler(config Config) (*JobCrawler, error) {
+	names, err := config.enabledSources()
+	if err != nil {
+		return nil, err
+	}
+
+	clients := config.Clients
+	if clients == nil {
+		if clients, err = NewClientFactory(config.HTTP); err != nil {
+			return nil, err
+		}
+	}
+
+	selectors, err := LoadSelectors(config.SelectorsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []Source
+	for _, name := range names {
+		source := lookupFactory(name)(SourceOptions{
+			Config:    config.sourceConfig(name),
+			Client:    clients.Client(name),
+			Selectors: selectors,
+		})
+		if source == nil {
+			log.Printf("[%s] Source is not configured, skipping", name)
+			continue
+		}
+		sources = append(sources, source)
+	}
+	jc := NewJobCrawlerWithSources(config, sources...)
+	if config.Enrich.Enabled {
+		jc.enricher = NewEnricher(config.Enrich, clients, selectors)
+	}
+	return jc, nil
+}
+
+// NewJobCrawlerWithSources builds a crawler over an explicit set of sources,
+// bypassing the registry and the config's enabled/disabled lists.
+func NewJobCrawlerWithSources(config Config, sources ...Source) *JobCrawler {
+	return &JobCrawler{
+		config:  config,
+		sources: sources,
+	}
+}
+
+// SourceNames returns the names of the sources a search crawls, in order.
+func (jc *JobCrawler) SourceNames() []string {
+	names := make([]string, len(jc.sources))
+	for i, s := range jc.sources {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// politeGate spaces out consecutive crawls of one source.
+type politeGate struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait reserves the next free slot for the source and sleeps until it opens.
+func (g *politeGate) wait(ctx context.Context, delay time.Duration) error {
+	g.mu.Lock()
+	now := time.Now()
+	start := now
+	if g.next.After(now) {
+		start = g.next
 	}
+	g.next = start.Add(delay)
+	g.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (jc *JobCrawler) gate(s Source) *politeGate {
+	jc.gatesMu.Lock()
+	defer jc.gatesMu.Unlock()
+	if jc.gates == nil {
+		jc.gates = make(map[Source]*politeGate)
+	}
+	g, ok := jc.gates[s]
+	if !ok {
+		g = &politeGate{}
+		jc.gates[s] = g
+	}
+	return g
+}
+
+// runSource crawls a single source under its own deadline, reporting its
+// progress to the context's SearchTrace.
+func (jc *JobCrawler) runSource(ctx context.Context, source Source, params JobSearchParams) ([]models.Job, SourceStatus) {
+	jobs, status := jc.crawlSource(ctx, source, params)
+	ContextSearchTrace(ctx).sourceDone(status, jobs)
+	return jobs, status
 }
 
-// getSourceName returns a human-readable name for a crawler source
-func getSourceName(s Source) string {
-	switch s.(type) {
-	case *LinkedInCrawler:
-		return "LinkedIn"
-	case *IndeedCrawler:
-		return "Indeed"
-	case *MonsterCrawler:
-		return "Monster"
-	case *GlassdoorCrawler:
-		return "Glassdoor"
-	default:
-		return "Unknown"
+func (jc *JobCrawler) crawlSource(ctx context.Context, source Source, params JobSearchParams) ([]models.Job, SourceStatus) {
+	sourceName := source.Name()
+	sc := jc.config.sourceConfig(sourceName)
+	status := SourceStatus{Name: sourceName}
+
+	if err := jc.gate(source).wait(ctx, sc.Delay); err != nil {
+		classifyError(&status, err)
+		return nil, status
+	}
+	ContextSearchTrace(ctx).sourceStarted(sourceName)
+
+	if sc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sc.Timeout)
+		defer cancel()
 	}
+
+	log.Printf("[%s] Starting search for %s in %s", sourceName, params.Title, params.Location)
+	start := time.Now()
+	jobs, err := source.Crawl(ctx, params)
+	status.Duration = time.Since(start)
+	if err != nil {
+		// A source that overran its deadline often reports the transport
+		// error rather than the context's, so prefer the latter.
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w: %v", ctx.Err(), err)
+		}
+		classifyError(&status, err)
+		log.Printf("[%s] Failed after %v (%s): %v", sourceName, status.Duration.Round(time.Millisecond), status.ErrorClass, err)
+		return nil, status
+	}
+	for i := range jobs {
+		normalizeJob(&jobs[i])
+	}
+	if filtered := FilterJobs(jobs, params, source.Capabilities().Filters); len(filtered) < len(jobs) {
+		log.Printf("[%s] Filtered out %d of %d jobs not matching the search", sourceName, len(jobs)-len(filtered), len(jobs))
+		jobs = filtered
+	}
+	status.JobCount = len(jobs)
+	status.FillRates = FillRates(jobs)
+
+	log.Printf("[%s] Found %d jobs with titles: %v", sourceName, len(jobs), func() []string {
+		var titles []string
+		for i, j := range jobs {
+			if i < 3 { // Only show first 3 jobs to avoid log spam
+				titles = append(titles, j.Title)
+			}
+		}
+		if len(jobs) > 3 {
+			titles = append(titles, "...")
+		}
+		return titles
+	}())
+	return jobs, status
 }
 
-func (jc *JobCrawler) SearchJobs(ctx context.Context, params JobSearchParams) ([]models.Job, error) {
+// SearchJobs crawls every source through a bounded worker pool. Results are
+// merged in source order regardless of which source finishes first, and
+// listings of the same job on several sources are combined by Dedupe. A
+// failing source does not fail the search; it is reported in the result's
+// Sources.
+func (jc *JobCrawler) SearchJobs(ctx context.Context, params JobSearchParams) (*SearchResult, error) {
 	log.Printf("Starting job search with %d sources: %v", len(jc.sources), func() []string {
 		var names []string
 		for _, s := range jc.sources {
-			names = append(names, getSourceName(s))
+			names = append(names, s.Name())
 		}
 		return names
 	}())
-	var (
-		results []models.Job
-		errors  []error
-	)
-
-	// Create a timeout context for each crawler
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 2*time.Minute) // Increased timeout since we're running sequentially
-	defer cancel()
-
-	for _, source := range jc.sources {
-		sourceName := getSourceName(source)
-		log.Printf("[%s] Starting search for %s in %s", sourceName, params.Title, params.Location)
-
-		// Run crawler with timeout
-		jobs, err := source.Crawl(ctxWithTimeout, params)
-		
-		if err != nil {
-			if ctxWithTimeout.Err() != nil {
-				log.Printf("[%s] Timed out", sourceName)
-			} else {
-				log.Printf("[%s] Error: %v", sourceName, err)
-			}
-			errors = append(errors, err)
-			continue // Continue with next source even if this one fails
-		}
 
-		log.Printf("[%s] Found %d jobs with titles: %v", sourceName, len(jobs), func() []string {
-			var titles []string
-			for i, j := range jobs {
-				if i < 3 { // Only show first 3 jobs to avoid log spam
-					titles = append(titles, j.Title)
-				}
-			}
-			if len(jobs) > 3 {
-				titles = append(titles, "...")
+	found := make([][]models.Job, len(jc.sources))
+	statuses := make([]SourceStatus, len(jc.sources))
+
+	workers := jc.config.Concurrency
+	if workers <= 0 || workers > len(jc.sources) {
+		workers = len(jc.sources)
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				found[i], statuses[i] = jc.runSource(ctx, jc.sources[i], params)
 			}
-			return titles
-		}())
-		results = append(results, jobs...)
+		}()
+	}
+	for i := range jc.sources {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
 
-		// Add a small delay between crawlers to be nice to the servers
-		time.Sleep(2 * time.Second)
+	result := &SearchResult{Sources: statuses}
+	for _, jobs := range found {
+		result.Jobs = append(result.Jobs, jobs...)
+	}
+	if n := len(result.Jobs); n > 0 {
+		result.Jobs = Dedupe(result.Jobs, jc.config.Dedupe)
+		if merged := n - len(result.Jobs); merged > 0 {
+			log.Printf("Merged %d duplicate listings across sources", merged)
+		}
+	}
+	if jc.enricher != nil {
+		jc.enricher.Enrich(ctx, result.Jobs)
+		// Jobs kept for lack of a description are checked again with the
+		// one their detail page had.
+		if params.Query != nil {
+			result.Jobs = slices.DeleteFunc(result.Jobs, func(job models.Job) bool {
+				return !params.Query.Match(job)
+			})
+		}
 	}
 
 	// Log summary
-	log.Printf("Search complete. Found %d total jobs", len(results))
-	if len(errors) > 0 {
-		log.Printf("Warning: %d sources had errors", len(errors))
+	log.Printf("Search complete. Found %d total jobs", len(result.Jobs))
+	if failed := result.Failed(); len(failed) > 0 {
+		var names []string
+		for _, s := range failed {
+			names = append(names, fmt.Sprintf("%s (%s)", s.Name, s.ErrorClass))
+		}
+		log.Printf("Warning: %d sources had errors: %s", len(failed), strings.Join(names, ", "))
 	}
 
-	return results, nil
+	return result, nil
 }
@@ -2,15 +2,31 @@ package crawler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"io"
 	"job-hunter/internal/models"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+// fixturePage returns the first recorded response body of a source's fixture.
+func fixturePage(t *testing.T, source string) string {
+	t.Helper()
+	fixture, err := LoadFixture("testdata/fixtures/" + source + ".json")
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	return fixture.Exchanges[0].Response.Body
+}
+
 func TestLinkedInCrawler(t *testing.T) {
+	page := fixturePage(t, "linkedin")
+
 	// Create a mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify request parameters
@@ -18,26 +34,17 @@ func TestLinkedInCrawler(t *testing.T) {
 			t.Errorf("Expected keywords=golang, got %s", r.URL.Query().Get("keywords"))
 		}
 
-		// Return mock response
-		w.Header().Set("Content-Type", "application/json")
-		mockJobs := []models.Job{
-			{
-				ID:         "1",
-				Title:      "Software Engineer",
-				Company:    "Test Company",
-				Location:   "Remote",
-				PostedDate: time.Now(),
-				Source:     "LinkedIn",
-			},
-		}
-		json.NewEncoder(w).Encode(mockJobs)
+		// Return a recorded results page
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
 	}))
 	defer server.Close()
 
 	// Create a test crawler with the mock server URL
-	crawler := &LinkedInCrawler{
-		client: &http.Client{Timeout: 10 * time.Second},
-	}
+	crawler := NewLinkedInCrawler(SourceOptions{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Config: SourceConfig{Pagination: Pagination{MaxPages: 1}},
+	})
 
 	// Override the baseURL for testing
 	oldURL := baseLinkedInURL
@@ -53,7 +60,7 @@ func TestLinkedInCrawler(t *testing.T) {
 	}
 
 	if len(jobs) == 0 {
-		t.Error("Expected jobs, got empty result")
+		t.Fatal("Expected jobs, got empty result")
 	}
 
 	if jobs[0].Title != "Software Engineer" {
@@ -62,38 +69,27 @@ func TestLinkedInCrawler(t *testing.T) {
 }
 
 func TestIndeedCrawler(t *testing.T) {
+	page := fixturePage(t, "indeed")
+
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify request parameters
 		if r.URL.Query().Get("q") != "golang" {
 			t.Errorf("Expected q=golang, got %s", r.URL.Query().Get("q"))
 		}
-		if r.URL.Query().Get("format") != "json" {
-			t.Errorf("Expected format=json, got %s", r.URL.Query().Get("format"))
+		if r.URL.Query().Get("sort") != "date" {
+			t.Errorf("Expected sort=date, got %s", r.URL.Query().Get("sort"))
 		}
 
-		// Return mock response
-		w.Header().Set("Content-Type", "application/json")
-		mockResponse := struct {
-			Results []models.Job `json:"results"`
-		}{
-			Results: []models.Job{
-				{
-					ID:         "2",
-					Title:      "Backend Developer",
-					Company:    "Test Corp",
-					Location:   "San Francisco",
-					PostedDate: time.Now(),
-					Source:     "Indeed",
-				},
-			},
-		}
-		json.NewEncoder(w).Encode(mockResponse)
+		// Return a recorded results page
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
 	}))
 	defer server.Close()
 
-	crawler := &IndeedCrawler{
-		client: &http.Client{Timeout: 10 * time.Second},
-	}
+	crawler := NewIndeedCrawler(SourceOptions{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Config: SourceConfig{Pagination: Pagination{MaxPages: 1}},
+	})
 
 	// Override the baseURL for testing
 	oldURL := baseIndeedURL
@@ -109,11 +105,11 @@ func TestIndeedCrawler(t *testing.T) {
 	}
 
 	if len(jobs) == 0 {
-		t.Error("Expected jobs, got empty result")
+		t.Fatal("Expected jobs, got empty result")
 	}
 
-	if jobs[0].Title != "Backend Developer" {
-		t.Errorf("Expected job title 'Backend Developer', got '%s'", jobs[0].Title)
+	if jobs[0].Title != "Software Engineer, Platform" {
+		t.Errorf("Expected job title 'Software Engineer, Platform', got '%s'", jobs[0].Title)
 	}
 }
 
@@ -122,11 +118,9 @@ func TestJobCrawlerConcurrency(t *testing.T) {
 	mockSource1 := &mockSource{jobs: []models.Job{{ID: "1", Source: "Mock1"}}}
 	mockSource2 := &mockSource{jobs: []models.Job{{ID: "2", Source: "Mock2"}}}
 
-	crawler := &JobCrawler{
-		sources: []Source{mockSource1, mockSource2},
-	}
+	crawler := NewJobCrawlerWithSources(Config{}, mockSource1, mockSource2)
 
-	jobs, err := crawler.SearchJobs(context.Background(), JobSearchParams{
+	result, err := crawler.SearchJobs(context.Background(), JobSearchParams{
 		Title:    "Software Engineer",
 		Location: "San Francisco",
 	})
@@ -134,16 +128,278 @@ func TestJobCrawlerConcurrency(t *testing.T) {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	if len(jobs) != 2 {
-		t.Errorf("Expected 2 jobs, got %d", len(jobs))
+	if len(result.Jobs) != 2 {
+		t.Errorf("Expected 2 jobs, got %d", len(result.Jobs))
+	}
+}
+
+func TestJobCrawlerParallelOrderAndTimeout(t *testing.T) {
+	slow := &mockSource{jobs: []models.Job{{ID: "slow", Source: "Slow"}}, delay: 200 * time.Millisecond}
+	stuck := &mockSource{jobs: []models.Job{{ID: "stuck", Source: "Stuck"}}, delay: time.Hour}
+	fast := &mockSource{jobs: []models.Job{{ID: "fast", Source: "Fast"}}}
+
+	crawler := NewJobCrawlerWithSources(Config{SourceTimeout: 500 * time.Millisecond}, slow, stuck, fast)
+
+	start := time.Now()
+	result, err := crawler.SearchJobs(context.Background(), JobSearchParams{Title: "golang"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected sources to run in parallel, search took %v", elapsed)
+	}
+
+	// The stuck source times out; the others keep their source order even
+	// though the fast one finishes first.
+	jobs := result.Jobs
+	if len(jobs) != 2 || jobs[0].ID != "slow" || jobs[1].ID != "fast" {
+		t.Errorf("Expected [slow fast], got %v", jobs)
+	}
+	if s := result.Sources[1]; s.ErrorClass != ErrorClassTimeout {
+		t.Errorf("Expected stuck source to time out, got %+v", s)
+	}
+}
+
+func TestJobCrawlerSourceStatus(t *testing.T) {
+	empty := &mockSource{}
+	blocked := &mockSource{err: &StatusError{StatusCode: 999}}
+	broken := &mockSource{err: &ParseError{Err: errors.New("unexpected EOF")}}
+
+	crawler := NewJobCrawlerWithSources(Config{}, empty, blocked, broken)
+
+	result, err := crawler.SearchJobs(context.Background(), JobSearchParams{Title: "golang"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !result.Sources[0].OK() || result.Sources[0].JobCount != 0 {
+		t.Errorf("Expected empty source to succeed with no jobs, got %+v", result.Sources[0])
+	}
+	if s := result.Sources[1]; s.ErrorClass != ErrorClassHTTPStatus || s.StatusCode != 999 || !s.Blocked {
+		t.Errorf("Expected blocked HTTP status, got %+v", s)
+	}
+	if s := result.Sources[2]; s.ErrorClass != ErrorClassParse || s.Blocked {
+		t.Errorf("Expected parse failure, got %+v", s)
+	}
+	if len(result.Failed()) != 2 {
+		t.Errorf("Expected 2 failed sources, got %d", len(result.Failed()))
+	}
+}
+
+func TestSearchTrace(t *testing.T) {
+	ok := &mockSource{name: "OK", jobs: []models.Job{{ID: "1", Source: "OK"}, {ID: "2", Source: "OK"}}}
+	broken := &mockSource{name: "Broken", err: &ParseError{Err: errors.New("unexpected EOF")}}
+	crawler := NewJobCrawlerWithSources(Config{}, ok, broken)
+
+	var mu sync.Mutex
+	started := make(map[string]bool)
+	done := make(map[string]int)
+	ctx := WithSearchTrace(context.Background(), &SearchTrace{
+		SourceStarted: func(source string) {
+			mu.Lock()
+			defer mu.Unlock()
+			started[source] = true
+		},
+		SourceDone: func(status SourceStatus, jobs []models.Job) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !started[status.Name] {
+				t.Errorf("Expected %s to start before it finished", status.Name)
+			}
+			done[status.Name] = len(jobs)
+			if status.Name == "Broken" && status.OK() {
+				t.Errorf("Expected the broken source's status to carry its error, got %+v", status)
+			}
+		},
+	})
+	if _, err := crawler.SearchJobs(ctx, JobSearchParams{Title: "golang"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(done) != 2 || done["OK"] != 2 || done["Broken"] != 0 {
+		t.Errorf("Expected both sources to report, got %v", done)
+	}
+	if names := crawler.SourceNames(); len(names) != 2 || names[0] != "OK" || names[1] != "Broken" {
+		t.Errorf("Expected [OK Broken], got %v", names)
+	}
+}
+
+func TestRegisteredOrder(t *testing.T) {
+	if got, want := Registered(), []string{"LinkedIn", "Indeed", "Monster", "Glassdoor", "Careers"}; !slices.Equal(got, want) {
+		t.Errorf("Expected sources in the order %v, got %v", want, got)
+	}
+}
+
+func TestNewJobCrawlerEnabledSources(t *testing.T) {
+	crawler, err := NewJobCrawler(Config{EnabledSources: []string{"indeed", " LinkedIn"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(crawler.sources) != 2 || crawler.sources[0].Name() != "Indeed" || crawler.sources[1].Name() != "LinkedIn" {
+		t.Errorf("Expected [Indeed LinkedIn], got %v", crawler.sources)
+	}
+
+	crawler, err = NewJobCrawler(Config{DisabledSources: []string{"Glassdoor"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, s := range crawler.sources {
+		if s.Name() == "Glassdoor" {
+			t.Error("Expected Glassdoor to be disabled")
+		}
+	}
+
+	if _, err := NewJobCrawler(Config{EnabledSources: []string{"Dice"}}); err == nil {
+		t.Error("Expected an error for an unregistered source")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	now := time.Date(2025, 4, 17, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	pages := [][]models.Job{
+		{{ID: "a", PostedDate: now}, {ID: "b", PostedDate: now.AddDate(0, 0, -1)}},
+		{{ID: "b"}, {ID: "c", PostedDate: now.AddDate(0, 0, -2)}, {ID: "old1", PostedDate: now.AddDate(0, 0, -30)}},
+		{{ID: "old2", PostedDate: now.AddDate(0, 0, -31)}},
+		{{ID: "never-fetched"}},
+	}
+	var fetched int
+	fetch := func(ctx context.Context, page int) ([]models.Job, error) {
+		fetched++
+		if page >= len(pages) {
+			return nil, nil
+		}
+		return pages[page], nil
+	}
+
+	cfg := SourceConfig{Pagination: Pagination{MaxPages: 10, MaxAge: 7 * 24 * time.Hour}}
+	jobs, err := paginate(context.Background(), "Mock", cfg, fetch)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(jobs) != 3 || jobs[2].ID != "c" {
+		t.Errorf("Expected [a b c], got %v", jobs)
+	}
+	if fetched != 3 {
+		t.Errorf("Expected paging to stop after the stale page, fetched %d pages", fetched)
+	}
+
+	fetched = 0
+	cfg = SourceConfig{Pagination: Pagination{MaxPages: 10, MaxJobs: 2}}
+	jobs, _ = paginate(context.Background(), "Mock", cfg, fetch)
+	if len(jobs) != 2 || fetched != 1 {
+		t.Errorf("Expected 2 jobs from 1 page, got %d jobs from %d pages", len(jobs), fetched)
+	}
+}
+
+func TestParsePostedDate(t *testing.T) {
+	now := time.Date(2025, 4, 17, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	tests := map[string]time.Time{
+		"2025-04-10":        time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC),
+		"Just posted":       now,
+		"Posted 3 days ago": now.AddDate(0, 0, -3),
+		"30+ days ago":      now.AddDate(0, 0, -30),
+		"24h":               now.Add(-24 * time.Hour),
+		"2w":                now.AddDate(0, 0, -14),
+		"1 month ago":       now.AddDate(0, -1, 0),
+		"Hiring urgently":   {},
+	}
+	for text, want := range tests {
+		if got := parsePostedDate(text); !got.Equal(want) {
+			t.Errorf("parsePostedDate(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientFactory(t *testing.T) {
+	var seen []*http.Request
+	fake := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": {"session=abc; Path=/"}},
+			Body:       io.NopCloser(strings.NewReader("<html></html>")),
+			Request:    req,
+		}, nil
+	})
+
+	factory, err := NewClientFactory(HTTPConfig{
+		UserAgents: []string{"agent-a", "agent-b"},
+		Transport:  fake,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	client := factory.Client("Mock")
+
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	for i := 0; i < 2; i++ {
+		if _, err := getPage(context.Background(), client, "Mock", "https://jobs.example.com/search", header); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(seen))
+	}
+	if seen[0].Header.Get("User-Agent") != "agent-a" || seen[1].Header.Get("User-Agent") != "agent-b" {
+		t.Errorf("Expected rotating user agents, got %q and %q", seen[0].Header.Get("User-Agent"), seen[1].Header.Get("User-Agent"))
+	}
+	if got := seen[0].Header.Get("Accept"); got != "application/json" {
+		t.Errorf("Expected the crawler's Accept header to win, got %q", got)
+	}
+	if seen[0].Header.Get("Accept-Language") == "" {
+		t.Error("Expected default browser headers to be set")
+	}
+	if got := seen[1].Header.Get("Cookie"); got != "session=abc" {
+		t.Errorf("Expected the cookie jar to replay the session cookie, got %q", got)
+	}
+	if got := sourceFromContext(seen[0].Context()); got != "Mock" {
+		t.Errorf("Expected request to be tagged with its source, got %q", got)
+	}
+
+	if _, err := NewClientFactory(HTTPConfig{ProxyURL: "ftp://proxy.example.com"}); err == nil {
+		t.Error("Expected an error for an unsupported proxy scheme")
 	}
 }
 
 // Mock source for testing
 type mockSource struct {
-	jobs []models.Job
+	name  string
+	jobs  []models.Job
+	delay time.Duration
+	err   error
+}
+
+func (m *mockSource) Name() string {
+	if m.name == "" {
+		return "Mock"
+	}
+	return m.name
+}
+
+func (m *mockSource) Capabilities() Capabilities {
+	return Capabilities{}
 }
 
 func (m *mockSource) Crawl(ctx context.Context, params JobSearchParams) ([]models.Job, error) {
-	return m.jobs, nil
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return m.jobs, m.err
 }
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var relativeDateRe = regexp.MustCompile(`(\d+)\+?\s*(minutes?|mins?|m|hours?|hrs?|h|days?|d|weeks?|w|months?|mo)\b`)
+
+// parsePostedDate turns the posted-date text shown on job cards into a time.
+// It understands ISO dates ("2025-04-10") as well as the relative forms the
+// sites use ("Just posted", "3 days ago", "30+ days ago", "24h", "2d").
+// It returns the zero time when the text can't be read.
+func parsePostedDate(text string) time.Time {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t
+		}
+	}
+
+	now := timeNow()
+	switch {
+	case strings.Contains(text, "just posted"), strings.Contains(text, "today"),
+		strings.Contains(text, "just now"):
+		return now
+	case strings.Contains(text, "yesterday"):
+		return now.AddDate(0, 0, -1)
+	}
+
+	m := relativeDateRe.FindStringSubmatch(text)
+	if m == nil {
+		return time.Time{}
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return time.Time{}
+	}
+	switch unit := m[2]; {
+	case strings.HasPrefix(unit, "mo"):
+		return now.AddDate(0, -n, 0)
+	case strings.HasPrefix(unit, "m"):
+		return now.Add(-time.Duration(n) * time.Minute)
+	case strings.HasPrefix(unit, "h"):
+		return now.Add(-time.Duration(n) * time.Hour)
+	case strings.HasPrefix(unit, "d"):
+		return now.AddDate(0, 0, -n)
+	case strings.HasPrefix(unit, "w"):
+		return now.AddDate(0, 0, -7*n)
+	}
+	return time.Time{}
+}
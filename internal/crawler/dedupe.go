@@ -0,0 +1,362 @@
+package crawler
+
+import (
+	"strings"
+	"unicode"
+
+	"job-hunter/internal/models"
+)
+
+// DedupeConfig controls how listings of the same job on different sources
+// are merged. Zero thresholds take their value from DefaultDedupeConfig.
+type DedupeConfig struct {
+	// Disabled keeps every listing as its own job.
+	Disabled bool
+	// TitleSimilarity is how alike two titles at the same company and
+	// location must be, from 0 to 1, to count as the same job.
+	TitleSimilarity float64
+	// LooseTitleSimilarity is the lower bar titles must clear when both
+	// jobs have descriptions that are at least DescriptionSimilarity alike.
+	LooseTitleSimilarity float64
+	// DescriptionSimilarity is the share of word shingles two descriptions
+	// must have in common.
+	DescriptionSimilarity float64
+}
+
+// DefaultDedupeConfig returns the thresholds used by DefaultConfig.
+func DefaultDedupeConfig() DedupeConfig {
+	return DedupeConfig{
+		TitleSimilarity:       0.85,
+		LooseTitleSimilarity:  0.6,
+		DescriptionSimilarity: 0.5,
+	}
+}
+
+// merge returns c with every zero threshold filled in from defaults.
+func (c DedupeConfig) merge(defaults DedupeConfig) DedupeConfig {
+	if c.TitleSimilarity == 0 {
+		c.TitleSimilarity = defaults.TitleSimilarity
+	}
+	if c.LooseTitleSimilarity == 0 {
+		c.LooseTitleSimilarity = defaults.LooseTitleSimilarity
+	}
+	if c.DescriptionSimilarity == 0 {
+		c.DescriptionSimilarity = defaults.DescriptionSimilarity
+	}
+	return c
+}
+
+// Dedupe merges listings of the same job found on different sources. Jobs
+// match when their companies are the same once legal suffixes are dropped,
+// their locations agree and their titles are similar enough, with
+// description overlap lowering the bar for the title. Two listings from the
+// same source are never merged: they are separate openings.
+//
+// Each cluster is replaced by its most complete listing, in the position of
+// its first one, with gaps filled from the others and Sources listing all
+// of them.
+func Dedupe(jobs []models.Job, config DedupeConfig) []models.Job {
+	if config.Disabled || len(jobs) < 2 {
+		return jobs
+	}
+	config = config.merge(DefaultDedupeConfig())
+
+	keys := make([]dedupeKey, len(jobs))
+	for i, job := range jobs {
+		keys[i] = newDedupeKey(job)
+	}
+
+	// Single-linkage clustering within each company.
+	var clusters [][]int
+	byCompany := make(map[string][]int) // company -> cluster indexes
+	for i := range jobs {
+		company := keys[i].company
+		joined := -1
+		if company != "" {
+			for _, c := range byCompany[company] {
+				if config.matchesCluster(jobs, keys, clusters[c], i) {
+					joined = c
+					break
+				}
+			}
+		}
+		if joined >= 0 {
+			clusters[joined] = append(clusters[joined], i)
+			continue
+		}
+		byCompany[company] = append(byCompany[company], len(clusters))
+		clusters = append(clusters, []int{i})
+	}
+
+	merged := make([]models.Job, 0, len(clusters))
+	for _, cluster := range clusters {
+		merged = append(merged, mergeCluster(jobs, cluster))
+	}
+	return merged
+}
+
+func (c DedupeConfig) matchesCluster(jobs []models.Job, keys []dedupeKey, cluster []int, i int) bool {
+	for _, j := range cluster {
+		if strings.EqualFold(jobs[j].Source, jobs[i].Source) {
+			return false
+		}
+	}
+	for _, j := range cluster {
+		if c.same(keys[i], keys[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// same reports whether two listings at the same company are one job.
+func (c DedupeConfig) same(a, b dedupeKey) bool {
+	if !sameLocation(a, b) {
+		return false
+	}
+	sim := diceSimilarity(a.titleBigrams, b.titleBigrams)
+	if sim >= c.TitleSimilarity {
+		return true
+	}
+	return sim >= c.LooseTitleSimilarity && len(a.shingles) > 0 && len(b.shingles) > 0 &&
+		jaccard(a.shingles, b.shingles) >= c.DescriptionSimilarity
+}
+
+// dedupeKey holds the normalized forms of a job that Dedupe compares.
+type dedupeKey struct {
+	company      string
+	place        string
+	remote       bool
+	titleBigrams map[string]int
+	shingles     map[string]bool
+}
+
+func newDedupeKey(job models.Job) dedupeKey {
+	key := dedupeKey{
+		company:      companyKey(job.Company),
+		titleBigrams: bigrams(titleKey(job.Title)),
+		shingles:     shingles(job.Description, 5),
+	}
+	key.place, key.remote = locationKey(job.Location)
+	return key
+}
+
+// Fingerprint identifies a job by its company, title and location,
+// normalized the way Dedupe compares them, so a job posted again under a new
+// listing ID can be recognized. It is empty when the job has no company or
+// title.
+func Fingerprint(job models.Job) string {
+	company, title := companyKey(job.Company), titleKey(job.Title)
+	if company == "" || title == "" {
+		return ""
+	}
+	place, remote := locationKey(job.Location)
+	if remote {
+		place = "remote"
+	}
+	return company + "|" + title + "|" + place
+}
+
+// companySuffixes are dropped from the end of company names.
+var companySuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "llc": true, "llp": true, "ltd": true, "limited": true,
+	"corp": true, "corporation": true, "co": true, "company": true, "plc": true,
+	"gmbh": true, "ag": true, "sa": true, "sas": true, "bv": true, "nv": true, "srl": true, "pty": true,
+}
+
+// companyKey reduces a company name to its lowercased words without legal
+// suffixes, so "Acme, Inc." and "ACME Inc" compare equal.
+func companyKey(company string) string {
+	words := strings.FieldsFunc(strings.ToLower(company), func(r rune) bool {
+		return !isWordRune(r)
+	})
+	for len(words) > 1 && companySuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// titleAbbreviations expands the abbreviations sites use in titles.
+var titleAbbreviations = map[string]string{
+	"sr": "senior", "jr": "junior", "snr": "senior",
+	"eng": "engineer", "engr": "engineer", "dev": "developer",
+	"mgr": "manager", "mgmt": "management", "admin": "administrator",
+	"swe": "software engineer", "sre": "site reliability engineer",
+	"i": "1", "ii": "2", "iii": "3", "iv": "4",
+}
+
+// titleKey lowercases a title, drops punctuation and expands abbreviations.
+func titleKey(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !isWordRune(r)
+	})
+	for i, w := range words {
+		if full, ok := titleAbbreviations[w]; ok {
+			words[i] = full
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// locationKey returns the first part of a location, the city or whatever
+// comes first, as lowercased words, and whether the location is remote.
+func locationKey(location string) (place string, remote bool) {
+	words := func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !isWordRune(r)
+		})
+	}
+	first, _, _ := strings.Cut(location, ",")
+	first, _, _ = strings.Cut(first, "(")
+	return strings.Join(words(first), " "), hasWord(words(location), "remote")
+}
+
+// sameLocation reports whether two listings can be in the same place: one
+// of them has no location, both are remote, or they name the same city.
+func sameLocation(a, b dedupeKey) bool {
+	if a.place == "" || b.place == "" {
+		return true
+	}
+	return (a.remote && b.remote) || a.place == b.place
+}
+
+func hasWord(words []string, w string) bool {
+	for _, word := range words {
+		if word == w {
+			return true
+		}
+	}
+	return false
+}
+
+// bigrams counts the character bigrams of s.
+func bigrams(s string) map[string]int {
+	runes := []rune(s)
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// diceSimilarity is the Sørensen–Dice coefficient of two bigram multisets.
+func diceSimilarity(a, b map[string]int) float64 {
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	shared := 0
+	for g, n := range a {
+		shared += min(n, b[g])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// shingles returns the set of k-word runs in text. Texts shorter than k
+// words have none.
+func shingles(text string, k int) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+	if len(words) < k {
+		return nil
+	}
+	set := make(map[string]bool, len(words)-k+1)
+	for i := 0; i+k <= len(words); i++ {
+		set[strings.Join(words[i:i+k], " ")] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#'
+}
+
+// mergeCluster returns the cluster's most complete job with gaps filled
+// from the other listings.
+func mergeCluster(jobs []models.Job, cluster []int) models.Job {
+	if len(cluster) == 1 {
+		return jobs[cluster[0]]
+	}
+
+	best := cluster[0]
+	for _, i := range cluster[1:] {
+		if completeness(jobs[i]) > completeness(jobs[best]) {
+			best = i
+		}
+	}
+	job := jobs[best]
+	job.Sources = []models.SourceRef{{Source: job.Source, ID: job.ID, URL: job.URL}}
+	for _, i := range cluster {
+		if i == best {
+			continue
+		}
+		other := jobs[i]
+		job.Sources = append(job.Sources, models.SourceRef{Source: other.Source, ID: other.ID, URL: other.URL})
+
+		if job.Location == "" {
+			job.Location = other.Location
+		}
+		if len(other.Description) > len(job.Description) {
+			job.Description, job.DescriptionHTML = other.Description, other.DescriptionHTML
+		}
+		if job.Salary == "" {
+			job.Salary = other.Salary
+		}
+		if job.SalaryMin == 0 && other.SalaryMin != 0 {
+			job.SalaryMin, job.SalaryMax = other.SalaryMin, other.SalaryMax
+			job.SalaryCurrency, job.SalaryPeriod = other.SalaryCurrency, other.SalaryPeriod
+		}
+		if job.EmploymentType == "" {
+			job.EmploymentType = other.EmploymentType
+		}
+		// The job has been open since the first site listed it.
+		if !other.PostedDate.IsZero() && (job.PostedDate.IsZero() || other.PostedDate.Before(job.PostedDate)) {
+			job.PostedDate = other.PostedDate
+		}
+	}
+	return job
+}
+
+// completeness scores how much of a job's data is filled in.
+func completeness(job models.Job) int {
+	score := 0
+	for _, set := range []bool{
+		job.Location != "",
+		job.URL != "",
+		job.Description != "",
+		job.Salary != "",
+		job.SalaryMin != 0,
+		job.EmploymentType != "",
+		!job.PostedDate.IsZero(),
+		!job.EnrichedAt.IsZero(),
+	} {
+		if set {
+			score++
+		}
+	}
+	return score
+}
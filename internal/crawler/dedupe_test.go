@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"job-hunter/internal/models"
+)
+
+func TestDedupe(t *testing.T) {
+	posted := time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC)
+	description := "We are hiring a backend engineer to build our payments platform in Go. You will own services end to end and work closely with product."
+	jobs := []models.Job{
+		{ID: "linkedin:1", Source: "LinkedIn", Title: "Senior Backend Engineer", Company: "Acme, Inc.", Location: "Austin, TX", URL: "https://www.linkedin.com/jobs/view/1"},
+		{ID: "linkedin:2", Source: "LinkedIn", Title: "Senior Backend Engineer", Company: "Acme, Inc.", Location: "Austin, TX", URL: "https://www.linkedin.com/jobs/view/2"},
+		{ID: "indeed:a", Source: "Indeed", Title: "Sr. Backend Engineer", Company: "ACME Inc", Location: "Austin, Texas", URL: "https://www.indeed.com/viewjob?jk=a", Salary: "$150,000 a year", PostedDate: posted},
+		{ID: "glassdoor:9", Source: "Glassdoor", Title: "Backend Engineer, Payments", Company: "Acme LLC", Location: "Austin, TX", URL: "https://www.glassdoor.com/job-listing/9", Description: description},
+		{ID: "monster:x", Source: "Monster", Title: "Senior Backend Engineer", Company: "Acme", Location: "Denver, CO", URL: "https://www.monster.com/job-openings/x"},
+		{ID: "indeed:b", Source: "Indeed", Title: "Senior Backend Engineer", Company: "Globex", Location: "Austin, TX", URL: "https://www.indeed.com/viewjob?jk=b"},
+	}
+
+	got := Dedupe(jobs, DedupeConfig{})
+	if len(got) != 5 {
+		t.Fatalf("Expected 5 jobs after merging, got %d: %+v", len(got), got)
+	}
+
+	// The Indeed listing has the most data, so it represents the cluster,
+	// in the place of the first listing.
+	merged := got[0]
+	if merged.ID != "indeed:a" || merged.Salary != "$150,000 a year" || !merged.PostedDate.Equal(posted) {
+		t.Errorf("Expected the Indeed listing to be canonical, got %+v", merged)
+	}
+	if len(merged.Sources) != 2 || merged.Sources[0].ID != "indeed:a" || merged.Sources[1].ID != "linkedin:1" {
+		t.Errorf("Expected the Indeed and first LinkedIn listings, got %+v", merged.Sources)
+	}
+	if merged.Description != "" {
+		t.Errorf("Expected no description to be borrowed from a job outside the cluster, got %q", merged.Description)
+	}
+
+	// A second LinkedIn posting is a separate opening, even with the same
+	// title; the Glassdoor one has a different title and no description to
+	// compare with, and the Monster and Globex jobs differ in place and company.
+	for i, id := range []string{"linkedin:2", "glassdoor:9", "monster:x", "indeed:b"} {
+		if got[i+1].ID != id || got[i+1].Sources != nil {
+			t.Errorf("Expected %s to stay on its own, got %+v", id, got[i+1])
+		}
+	}
+}
+
+func TestDedupeDescriptionLowersTitleBar(t *testing.T) {
+	description := "We are hiring a backend engineer to build our payments platform in Go. You will own services end to end and work closely with product."
+	jobs := []models.Job{
+		{ID: "linkedin:1", Source: "LinkedIn", Title: "Backend Engineer (Payments)", Company: "Acme", Location: "Remote", Description: description},
+		{ID: "glassdoor:9", Source: "Glassdoor", Title: "Software Engineer, Backend", Company: "Acme Corp", Location: "Remote - US", Description: description + " Apply today."},
+	}
+	if got := Dedupe(jobs, DedupeConfig{}); len(got) != 1 || len(got[0].Sources) != 2 {
+		t.Errorf("Expected matching descriptions to merge similar titles, got %+v", got)
+	}
+	if got := Dedupe(jobs, DedupeConfig{Disabled: true}); len(got) != 2 {
+		t.Errorf("Expected nothing to be merged when disabled, got %+v", got)
+	}
+}
+
+func TestCompanyKey(t *testing.T) {
+	for _, name := range []string{"Acme, Inc.", "ACME Inc", "The Acme Company", "Acme Corp.", "acme"} {
+		if got := companyKey(name); got != "acme" {
+			t.Errorf("companyKey(%q) = %q, want \"acme\"", name, got)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(models.Job{Title: "Sr. Backend Engineer", Company: "Acme, Inc.", Location: "Austin, TX"})
+	b := Fingerprint(models.Job{Title: "Senior Backend Engineer", Company: "ACME", Location: "Austin, Texas, United States"})
+	if a == "" || a != b {
+		t.Errorf("Expected the same job to have the same fingerprint, got %q and %q", a, b)
+	}
+	if c := Fingerprint(models.Job{Title: "Senior Backend Engineer", Company: "Acme", Location: "Denver, CO"}); c == a {
+		t.Errorf("Expected a different city to change the fingerprint, got %q", c)
+	}
+	if got := Fingerprint(models.Job{Title: "Backend Engineer"}); got != "" {
+		t.Errorf("Expected no fingerprint without a company, got %q", got)
+	}
+}
@@ -0,0 +1,356 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+)
+
+// EnrichConfig configures the second pass of a search, which reads each
+// job's own page for the details result pages leave out.
+type EnrichConfig struct {
+	// Enabled turns the pass on. It costs one request per job, so it is
+	// off by default.
+	Enabled bool
+	// Concurrency caps how many detail pages are fetched at the same time,
+	// across all sources. Zero means one at a time.
+	Concurrency int
+	// MaxJobs caps how many detail pages are fetched per search. Zero means
+	// no limit.
+	MaxJobs int
+	// Cache remembers what was read, so a job's page is fetched only once
+	// across runs. Nil means every search reads the pages again.
+	Cache EnrichmentCache
+}
+
+// Enrichment is what a job's detail page adds to it.
+type Enrichment struct {
+	Description     string    `json:"description,omitempty"`
+	DescriptionHTML string    `json:"description_html,omitempty"`
+	PostedDate      time.Time `json:"posted_date,omitzero"`
+	Salary          string    `json:"salary,omitempty"`
+	SalaryMin       float64   `json:"salary_min,omitempty"`
+	SalaryMax       float64   `json:"salary_max,omitempty"`
+	SalaryCurrency  string    `json:"salary_currency,omitempty"`
+	SalaryPeriod    string    `json:"salary_period,omitempty"`
+	EmploymentType  string    `json:"employment_type,omitempty"`
+	EnrichedAt      time.Time `json:"enriched_at"`
+}
+
+func (e Enrichment) empty() bool {
+	return e.Description == "" && e.PostedDate.IsZero() && e.Salary == "" && e.EmploymentType == ""
+}
+
+// apply copies the enrichment onto job. The full description replaces the
+// snippet from the results page; the other fields only fill gaps.
+func (e Enrichment) apply(job *models.Job) {
+	if e.Description != "" {
+		job.Description = e.Description
+		job.DescriptionHTML = e.DescriptionHTML
+	}
+	if job.PostedDate.IsZero() {
+		job.PostedDate = e.PostedDate
+	}
+	if job.Salary == "" {
+		job.Salary = e.Salary
+	}
+	if job.SalaryMin == 0 && e.SalaryMin != 0 {
+		job.SalaryMin, job.SalaryMax = e.SalaryMin, e.SalaryMax
+		job.SalaryCurrency, job.SalaryPeriod = e.SalaryCurrency, e.SalaryPeriod
+	}
+	if job.EmploymentType == "" {
+		job.EmploymentType = e.EmploymentType
+	}
+	job.EnrichedAt = e.EnrichedAt
+}
+
+// ExtractDetail reads a job's detail page: its JSON-LD JobPosting when it
+// has one, with any gaps filled from the detail section of spec. spec may
+// be nil for sources without one.
+func ExtractDetail(body []byte, pageURL string, spec *SelectorSpec) (Enrichment, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return Enrichment{}, &ParseError{Err: err}
+	}
+
+	var e Enrichment
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var data any
+		if json.Unmarshal([]byte(s.Text()), &data) != nil {
+			return true
+		}
+		postings := findJobPostings(data)
+		if len(postings) == 0 {
+			return true
+		}
+		base, _ := url.Parse(pageURL)
+		job := jobFromPosting(postings[0], "", base)
+		e = Enrichment{
+			Description:    job.Description,
+			PostedDate:     job.PostedDate,
+			Salary:         job.Salary,
+			SalaryMin:      job.SalaryMin,
+			SalaryMax:      job.SalaryMax,
+			SalaryCurrency: job.SalaryCurrency,
+			SalaryPeriod:   job.SalaryPeriod,
+			EmploymentType: job.EmploymentType,
+		}
+		if e.Description != "" {
+			e.DescriptionHTML = sanitizeHTML(jsonString(postings[0]["description"]))
+		}
+		return false
+	})
+
+	if spec != nil {
+		page := doc.Selection
+		if f, ok := spec.Detail["description"]; ok && e.Description == "" {
+			sel := page.Find(f.Selector).First()
+			if f.Exclude != "" {
+				sel = sel.Clone()
+				sel.Find(f.Exclude).Remove()
+			}
+			if h, _ := sel.Html(); h != "" {
+				e.DescriptionHTML = sanitizeHTML(h)
+				e.Description = htmlToText(e.DescriptionHTML)
+			}
+		}
+		if f, ok := spec.Detail["posted"]; ok && e.PostedDate.IsZero() {
+			e.PostedDate = parsePostedDate(spec.value(page, f))
+		}
+		if f, ok := spec.Detail["salary"]; ok && e.Salary == "" {
+			e.Salary = spec.value(page, f)
+		}
+		if f, ok := spec.Detail["employment_type"]; ok && e.EmploymentType == "" {
+			e.EmploymentType = normalizeEmploymentType(spec.value(page, f))
+		}
+	}
+
+	if e.empty() {
+		return Enrichment{}, &ParseError{Err: fmt.Errorf("no job details found")}
+	}
+	return e, nil
+}
+
+// Enricher reads the detail page of each job found by a search.
+type Enricher struct {
+	config    EnrichConfig
+	clients   *ClientFactory
+	selectors map[string]*SelectorSpec
+}
+
+// NewEnricher builds an enricher that fetches pages with clients from
+// clients, so detail pages share the sources' rate limits, and reads them
+// with the detail sections of selectors. A nil clients uses the default
+// factory.
+func NewEnricher(config EnrichConfig, clients *ClientFactory, selectors map[string]*SelectorSpec) *Enricher {
+	if clients == nil {
+		clients = defaultClientFactory()
+	}
+	return &Enricher{config: config, clients: clients, selectors: selectors}
+}
+
+// Enrich fills in jobs from their detail pages, in place. Jobs that were
+// enriched before, by an earlier search or through the cache, are not
+// fetched again. A page that fails is logged and its job left as it was.
+func (e *Enricher) Enrich(ctx context.Context, jobs []models.Job) {
+	log := logger.Get()
+
+	var pending []int
+	for i, job := range jobs {
+		if job.URL != "" && job.EnrichedAt.IsZero() {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	cached := 0
+	if e.config.Cache != nil {
+		ids := make([]string, len(pending))
+		for n, i := range pending {
+			ids[n] = jobs[i].ID
+		}
+		found, err := e.config.Cache.Lookup(ids)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to read enrichment cache")
+		}
+		remaining := pending[:0]
+		for _, i := range pending {
+			if enrichment, ok := found[jobs[i].ID]; ok {
+				enrichment.apply(&jobs[i])
+				normalizeJob(&jobs[i])
+				cached++
+				continue
+			}
+			remaining = append(remaining, i)
+		}
+		pending = remaining
+	}
+	if e.config.MaxJobs > 0 && len(pending) > e.config.MaxJobs {
+		pending = pending[:e.config.MaxJobs]
+	}
+
+	results := make([]*Enrichment, len(pending))
+	workers := e.config.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(pending); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				results[n] = e.fetch(ctx, jobs[pending[n]])
+			}
+		}()
+	}
+	for n := range pending {
+		queue <- n
+	}
+	close(queue)
+	wg.Wait()
+
+	fresh := make(map[string]Enrichment)
+	for n, enrichment := range results {
+		if enrichment == nil {
+			continue
+		}
+		job := &jobs[pending[n]]
+		enrichment.apply(job)
+		normalizeJob(job)
+		fresh[job.ID] = *enrichment
+	}
+	if e.config.Cache != nil && len(fresh) > 0 {
+		if err := e.config.Cache.Store(fresh); err != nil {
+			log.Warn().Err(err).Msg("Failed to save enrichment cache")
+		}
+	}
+
+	log.Info().Int("enriched", len(fresh)).Int("cached", cached).Int("failed", len(pending)-len(fresh)).Msg("Completed detail pages")
+}
+
+func (e *Enricher) fetch(ctx context.Context, job models.Job) *Enrichment {
+	log := logger.Get().With().Str("source", job.Source).Str("url", job.URL).Logger()
+
+	body, err := getPage(ctx, e.clients.Client(job.Source), job.Source, job.URL, nil)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch detail page")
+		return nil
+	}
+	spec, ok := e.selectors[strings.ToLower(job.Source)]
+	if !ok {
+		spec = builtinSelector(job.Source)
+	}
+	enrichment, err := ExtractDetail(body, job.URL, spec)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to read detail page")
+		return nil
+	}
+	enrichment.EnrichedAt = timeNow()
+	return &enrichment
+}
+
+// EnrichmentCache keeps enrichments between searches, keyed by job ID.
+type EnrichmentCache interface {
+	// Lookup returns the stored enrichments for those of ids it has.
+	Lookup(ids []string) (map[string]Enrichment, error)
+	// Store adds or replaces enrichments.
+	Store(found map[string]Enrichment) error
+}
+
+// FileEnrichmentCache is an EnrichmentCache kept in a JSON file. Entries
+// older than its max age are dropped, so a job whose listing changes is
+// read again eventually.
+type FileEnrichmentCache struct {
+	path   string
+	maxAge time.Duration
+
+	mu sync.Mutex
+}
+
+// NewFileEnrichmentCache returns a cache stored at path. A zero maxAge
+// keeps entries forever.
+func NewFileEnrichmentCache(path string, maxAge time.Duration) *FileEnrichmentCache {
+	return &FileEnrichmentCache{path: path, maxAge: maxAge}
+}
+
+func (c *FileEnrichmentCache) Lookup(ids []string) (map[string]Enrichment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]Enrichment)
+	for _, id := range ids {
+		if e, ok := entries[id]; ok {
+			found[id] = e
+		}
+	}
+	return found, nil
+}
+
+func (c *FileEnrichmentCache) Store(found map[string]Enrichment) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		return err
+	}
+	for id, e := range found {
+		entries[id] = e
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing enrichment cache: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// load reads the file, leaving out expired entries.
+func (c *FileEnrichmentCache) load() (map[string]Enrichment, error) {
+	entries := make(map[string]Enrichment)
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading enrichment cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing enrichment cache %s: %w", c.path, err)
+	}
+	if c.maxAge > 0 {
+		cutoff := timeNow().Add(-c.maxAge)
+		for id, e := range entries {
+			if e.EnrichedAt.Before(cutoff) {
+				delete(entries, id)
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"job-hunter/internal/models"
+)
+
+func TestExtractDetail(t *testing.T) {
+	now := time.Date(2025, 4, 14, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	body, err := os.ReadFile("testdata/detail/linkedin.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ExtractDetail(body, "https://www.linkedin.com/jobs/view/1", builtinSelector("LinkedIn"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	wantHTML := `<p>Join the <strong>platform</strong> team.</p>` + "\n      \n      " +
+		`<ul><li>Go</li><li><a>Kubernetes</a></li></ul>` + "\n      " +
+		`<p><a href="https://acme.example/benefits" rel="nofollow noopener">Benefits</a></p>`
+	if e.DescriptionHTML != wantHTML {
+		t.Errorf("Expected sanitized description HTML\n%s\ngot\n%s", wantHTML, e.DescriptionHTML)
+	}
+	if e.Description != "Join the platform team.\nGo\nKubernetes\nBenefits" {
+		t.Errorf("Expected description text, got %q", e.Description)
+	}
+	if want := now.AddDate(0, 0, -3); !e.PostedDate.Equal(want) {
+		t.Errorf("Expected posted date %v, got %v", want, e.PostedDate)
+	}
+	if e.Salary != "$140,000.00/yr - $170,000.00/yr" {
+		t.Errorf("Expected salary text, got %q", e.Salary)
+	}
+	if e.EmploymentType != "full_time" {
+		t.Errorf("Expected normalized employment type, got %q", e.EmploymentType)
+	}
+
+	// JSON-LD is preferred over the selectors.
+	e, err = ExtractDetail(readTestdata(t, "posting.html"), "https://boards.acme-robotics.example/jobs/4012345", builtinSelector("LinkedIn"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if e.SalaryMin != 150000 || e.EmploymentType != "full_time, contractor" {
+		t.Errorf("Expected the JSON-LD posting to be read, got %+v", e)
+	}
+	if want := `<p>We are looking for a <strong>Platform Engineer</strong> to run our Kubernetes fleet.</p><ul><li>Go</li><li>Terraform</li></ul>`; e.DescriptionHTML != want {
+		t.Errorf("Expected the entity-encoded description to be decoded and kept as HTML, got %q", e.DescriptionHTML)
+	}
+
+	if _, err := ExtractDetail([]byte("<p>Nothing here</p>"), "", nil); err == nil {
+		t.Error("Expected an error for a page without job details")
+	}
+}
+
+func TestEnricher(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/jobs/view/1":
+			http.ServeFile(w, r, "testdata/detail/linkedin.html")
+		case "/careers/4012345":
+			http.ServeFile(w, r, "testdata/jsonld/posting.html")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultHTTPConfig()
+	config.DefaultRate = 0
+	config.Retry = RetryPolicy{}
+	clients, err := NewClientFactory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewFileEnrichmentCache(filepath.Join(t.TempDir(), "enriched.json"), 30*24*time.Hour)
+	enricher := NewEnricher(EnrichConfig{Concurrency: 2, Cache: cache}, clients, nil)
+
+	jobs := func() []models.Job {
+		return []models.Job{
+			{ID: "linkedin-1", Source: "LinkedIn", URL: server.URL + "/jobs/view/1", Description: "Join the platform…", Salary: "$150k"},
+			{ID: "careers-1", Source: "Careers", URL: server.URL + "/careers/4012345"},
+			{ID: "linkedin-2", Source: "LinkedIn", URL: server.URL + "/jobs/view/missing"},
+			{ID: "linkedin-3", Source: "LinkedIn", URL: server.URL + "/jobs/view/1", EnrichedAt: time.Now()},
+		}
+	}
+
+	first := jobs()
+	enricher.Enrich(context.Background(), first)
+	if got := hits.Load(); got != 3 {
+		t.Errorf("Expected 3 detail pages fetched, got %d", got)
+	}
+	if first[0].DescriptionHTML == "" || first[0].EmploymentType != "full_time" || first[0].EnrichedAt.IsZero() {
+		t.Errorf("Expected the LinkedIn job to be enriched, got %+v", first[0])
+	}
+	if first[0].Salary != "$150k" {
+		t.Errorf("Expected the results page salary to be kept, got %q", first[0].Salary)
+	}
+	if first[1].Salary != "USD 150,000-185,000 per year" || first[1].PostedDate.IsZero() {
+		t.Errorf("Expected the careers job to be enriched from JSON-LD, got %+v", first[1])
+	}
+	if !first[2].EnrichedAt.IsZero() {
+		t.Errorf("Expected the failed page to leave its job alone, got %+v", first[2])
+	}
+
+	// A later run gets the enriched jobs from the cache and only retries
+	// the one that failed.
+	second := jobs()
+	enricher.Enrich(context.Background(), second)
+	if got := hits.Load(); got != 4 {
+		t.Errorf("Expected only the failed page to be fetched again, got %d requests", got)
+	}
+	if second[1].Salary != first[1].Salary || !second[1].EnrichedAt.Equal(first[1].EnrichedAt) {
+		t.Errorf("Expected the cached enrichment to be applied, got %+v", second[1])
+	}
+}
@@ -0,0 +1,201 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture is a set of HTTP exchanges captured from a live site, used to
+// replay a crawl without touching the network.
+type Fixture struct {
+	RecordedAt time.Time  `json:"recorded_at"`
+	Exchanges  []Exchange `json:"exchanges"`
+}
+
+// Exchange is one recorded request and the response it got.
+type Exchange struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the metadata of a recorded request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// RecordedResponse is a recorded response, body included.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// redactedHeaders are never written to a fixture.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range redactedHeaders {
+		h.Del(k)
+	}
+	return h
+}
+
+// LoadFixture reads a fixture written by RecordingTransport.Save.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture as indented JSON, leaving HTML bodies readable.
+func (f *Fixture) Save(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// RecordingTransport passes requests through to base and keeps a copy of
+// every exchange, minus cookies and credentials.
+type RecordingTransport struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecordingTransport wraps base, or http.DefaultTransport when base is nil.
+func NewRecordingTransport(base http.RoundTripper) *RecordingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RecordingTransport{base: base, fixture: Fixture{RecordedAt: time.Now().UTC()}}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	t.fixture.Exchanges = append(t.fixture.Exchanges, Exchange{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: redact(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redact(resp.Header),
+			Body:       string(body),
+		},
+	})
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// Fixture returns what has been recorded so far.
+func (t *RecordingTransport) Fixture() *Fixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f := t.fixture
+	f.Exchanges = append([]Exchange(nil), t.fixture.Exchanges...)
+	return &f
+}
+
+// Save writes what has been recorded so far to path.
+func (t *RecordingTransport) Save(path string) error {
+	return t.Fixture().Save(path)
+}
+
+// ReplayTransport answers requests from a fixture. Requests are matched on
+// method and URL; repeated requests for the same URL get the recorded
+// responses in order, the last one repeating. A request with no recording
+// fails rather than going to the network.
+type ReplayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]Exchange
+	served    map[string]int
+}
+
+// NewReplayTransport serves the exchanges in f.
+func NewReplayTransport(f *Fixture) *ReplayTransport {
+	t := &ReplayTransport{
+		exchanges: make(map[string][]Exchange),
+		served:    make(map[string]int),
+	}
+	for _, e := range f.Exchanges {
+		key := replayKey(e.Request.Method, e.Request.URL)
+		t.exchanges[key] = append(t.exchanges[key], e)
+	}
+	return t
+}
+
+func replayKey(method, url string) string {
+	return strings.ToUpper(method) + " " + url
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := replayKey(req.Method, req.URL.String())
+
+	t.mu.Lock()
+	recorded := t.exchanges[key]
+	if len(recorded) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+	i := t.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	t.served[key]++
+	t.mu.Unlock()
+
+	r := recorded[i].Response
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
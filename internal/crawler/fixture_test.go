@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	record = flag.Bool("record", false, "record fresh fixtures from the live sites into testdata/fixtures")
+	update = flag.Bool("update", false, "rewrite testdata/golden from the current parser output")
+)
+
+// goldenParams is the search every fixture is recorded with.
+var goldenParams = JobSearchParams{Title: "software engineer", Location: "Remote"}
+
+// TestCrawlerGolden replays each source's recorded fixture through its
+// crawler and compares the jobs with testdata/golden. When a site changes,
+// refresh both with:
+//
+//	go test ./internal/crawler -run TestCrawlerGolden -record -update
+func TestCrawlerGolden(t *testing.T) {
+	for _, name := range Registered() {
+		t.Run(name, func(t *testing.T) {
+			if lookupFactory(name)(SourceOptions{Client: http.DefaultClient}) == nil {
+				t.Skip("source needs configuration to run")
+			}
+			fixturePath := filepath.Join("testdata", "fixtures", strings.ToLower(name)+".json")
+			goldenPath := filepath.Join("testdata", "golden", strings.ToLower(name)+".json")
+
+			config := DefaultHTTPConfig()
+			config.Retry = RetryPolicy{}
+			var recorder *RecordingTransport
+			if *record {
+				recorder = NewRecordingTransport(nil)
+				config.Transport = recorder
+			} else {
+				fixture, err := LoadFixture(fixturePath)
+				if err != nil {
+					t.Fatalf("Failed to load fixture: %v", err)
+				}
+				config.Transport = NewReplayTransport(fixture)
+				config.DefaultRate = 0
+				config.HostRates = nil
+
+				// Relative dates ("3 days ago") are read as of the recording.
+				timeNow = func() time.Time { return fixture.RecordedAt }
+				defer func() { timeNow = time.Now }()
+			}
+
+			clients, err := NewClientFactory(config)
+			if err != nil {
+				t.Fatalf("Failed to create client factory: %v", err)
+			}
+			source := lookupFactory(name)(SourceOptions{
+				Config: SourceConfig{Pagination: Pagination{MaxPages: 1}},
+				Client: clients.Client(name),
+			})
+
+			jobs, err := source.Crawl(context.Background(), goldenParams)
+			if recorder != nil {
+				if err := recorder.Save(fixturePath); err != nil {
+					t.Fatalf("Failed to save fixture: %v", err)
+				}
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var got bytes.Buffer
+			enc := json.NewEncoder(&got)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(jobs); err != nil {
+				t.Fatalf("Failed to encode jobs: %v", err)
+			}
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got.Bytes(), 0644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("Jobs differ from %s (run with -update to accept):\n%s", goldenPath, got.String())
+			}
+		})
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("<p>" + r.URL.Query().Get("q") + "</p>"))
+	}))
+
+	recorder := NewRecordingTransport(nil)
+	client := &http.Client{Transport: recorder}
+	req, _ := http.NewRequest("GET", server.URL+"?q=go", nil)
+	req.Header.Set("Cookie", "session=secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Failed to save fixture: %v", err)
+	}
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	if len(fixture.Exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(fixture.Exchanges))
+	}
+	e := fixture.Exchanges[0]
+	if e.Request.Header.Get("Cookie") != "" || e.Response.Header.Get("Set-Cookie") != "" {
+		t.Error("Expected cookies to be redacted from the fixture")
+	}
+	if e.Response.Body != "<p>go</p>" {
+		t.Errorf("Expected recorded body, got %q", e.Response.Body)
+	}
+
+	// The replay serves the recording without the server.
+	server.Close()
+	client = &http.Client{Transport: NewReplayTransport(fixture)}
+	body, err := getPage(context.Background(), client, "Test", server.URL+"?q=go", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(body) != "<p>go</p>" {
+		t.Errorf("Expected replayed body, got %q", body)
+	}
+	if _, err := getPage(context.Background(), client, "Test", server.URL+"?q=rust", nil); err == nil {
+		t.Error("Expected an error for a request that wasn't recorded")
+	}
+}
@@ -1,28 +0,0 @@
-package crawler
-
-import (
-	"golang.org/x/net/html"
-	"strings"
-)
-
-// Helper function to check if a node has a specific class
-func hasClass(n *html.Node, class string) bool {
-	for _, a := range n.Attr {
-		if a.Key == "class" && strings.Contains(a.Val, class) {
-			return true
-		}
-	}
-	return false
-}
-
-// Helper function to get text content from a node
-func getTextContent(n *html.Node) string {
-	if n.Type == html.TextNode {
-		return strings.TrimSpace(n.Data)
-	}
-	var result string
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		result += getTextContent(c)
-	}
-	return strings.TrimSpace(result)
-}
@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"job-hunter/internal/models"
+)
+
+// JobID returns the stable ID of a job: "<source>:<native ID>" when the
+// site's own ID for the posting is known, e.g. "linkedin:3891234567", and
+// "<source>:h-<hash>" otherwise. The hash covers the normalized title,
+// company and location and the job URL without its query, so it survives
+// whitespace and tracking-parameter changes between crawls.
+func JobID(source, nativeID string, job models.Job) string {
+	prefix := strings.ToLower(source) + ":"
+	if nativeID != "" {
+		return prefix + nativeID
+	}
+
+	h := sha256.New()
+	for _, part := range []string{normalizeKey(job.Title), normalizeKey(job.Company), normalizeKey(job.Location), canonicalURL(job.URL)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return prefix + "h-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// nativeID returns the source's own ID for the job at jobURL, read with the
+// spec's IDPattern.
+func (s *SelectorSpec) nativeID(jobURL string) string {
+	if s == nil || s.idPattern == nil || jobURL == "" {
+		return ""
+	}
+	m := s.idPattern.FindStringSubmatch(jobURL)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+// LegacyJobIDs returns the forms the job's ID took before IDs were based on
+// native IDs: "<source>-<title>-<company>", query-escaped, as well as the
+// company-less form LinkedIn jobs got when the company wasn't read. They
+// are normalized with NormalizeLegacyJobID so stored IDs that picked up
+// stray whitespace still match.
+func LegacyJobIDs(job models.Job) []string {
+	source := strings.ToLower(job.Source)
+	return []string{
+		NormalizeLegacyJobID(source + "-" + url.QueryEscape(job.Title) + "-" + url.QueryEscape(job.Company)),
+		NormalizeLegacyJobID(source + "-" + url.QueryEscape(job.Title) + "-"),
+	}
+}
+
+// NormalizeLegacyJobID reduces an old-style ID to its lowercased letters and
+// digits. It returns "" for IDs that are already in the JobID format.
+func NormalizeLegacyJobID(id string) string {
+	if strings.Contains(id, ":") {
+		return ""
+	}
+	if unescaped, err := url.QueryUnescape(id); err == nil {
+		id = unescaped
+	}
+	return normalizeKey(id)
+}
+
+// normalizeKey lowercases s and drops everything but letters and digits.
+func normalizeKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// canonicalURL drops the scheme, query and fragment of u.
+func canonicalURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return strings.ToLower(parsed.Host) + strings.TrimSuffix(parsed.Path, "/")
+}
@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"testing"
+
+	"job-hunter/internal/models"
+)
+
+func TestJobID(t *testing.T) {
+	job := models.Job{Title: "Go Engineer", Company: "Acme", Location: "Remote", URL: "https://jobs.example.com/view/go-engineer?ref=search&pos=1"}
+	id := JobID("Example", "", job)
+
+	// Whitespace, case and tracking parameters don't change the hash.
+	same := models.Job{Title: "\n  go  engineer\n", Company: "ACME", Location: "remote", URL: "https://jobs.example.com/view/go-engineer?ref=alert"}
+	if got := JobID("Example", "", same); got != id {
+		t.Errorf("Expected the same ID for the same job, got %q and %q", id, got)
+	}
+	// Two openings with the same title at the same company don't collide.
+	other := job
+	other.URL = "https://jobs.example.com/view/go-engineer-2"
+	if JobID("Example", "", other) == id {
+		t.Error("Expected different IDs for different postings")
+	}
+	if got := JobID("LinkedIn", "3891234567", job); got != "linkedin:3891234567" {
+		t.Errorf("Expected the native ID to be used, got %q", got)
+	}
+}
+
+func TestLegacyJobIDs(t *testing.T) {
+	job := models.Job{Source: "LinkedIn", Title: "Director, Information Technology", Company: "Lenz"}
+
+	for _, old := range []string{
+		// Written before the title and company were whitespace-cleaned,
+		// when the company was read as empty.
+		"linkedin-%0A++++++++++++%0A++++++++Director%2C+Information+Technology%0A++++++%0A++++++++++-%0A++++++++++++++%0A++++++++++",
+		"linkedin-Director%2C+Information+Technology-Lenz",
+	} {
+		key := NormalizeLegacyJobID(old)
+		found := false
+		for _, legacy := range LegacyJobIDs(job) {
+			found = found || legacy == key
+		}
+		if !found {
+			t.Errorf("Expected %q to match the job", old)
+		}
+	}
+	if NormalizeLegacyJobID("linkedin:3891234567") != "" {
+		t.Error("Expected current IDs not to be treated as legacy")
+	}
+}
@@ -0,0 +1,365 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+)
+
+// ExtractJSONLD returns the schema.org JobPosting objects embedded as
+// JSON-LD in an HTML page. Postings are found at the top level of a
+// script block, inside @graph, and inside ItemList elements. Relative job
+// URLs are resolved against pageURL, which is also used when a posting has
+// no URL of its own. Blocks that aren't valid JSON are skipped.
+func ExtractJSONLD(body []byte, source, pageURL string) ([]models.Job, error) {
+	log := logger.Get().With().Str("source", source).Logger()
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	base, _ := url.Parse(pageURL)
+
+	var jobs []models.Job
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			log.Debug().Err(err).Msg("Skipping invalid JSON-LD block")
+			return
+		}
+		for _, posting := range findJobPostings(data) {
+			job := jobFromPosting(posting, source, base)
+			if job.Title == "" || job.Company == "" {
+				continue
+			}
+			jobs = append(jobs, job)
+		}
+	})
+	return jobs, nil
+}
+
+// extractJobs reads the jobs from a results page with the source's
+// selector spec and from its JSON-LD, merged: a card with a posting of the
+// same ID or URL is replaced by the posting, and postings without a card
+// are added. A page embedding JSON-LD for a single featured job still
+// yields all its cards, and one with only JSON-LD all its postings.
+func extractJobs(body []byte, pageURL string, spec *SelectorSpec) ([]models.Job, error) {
+	jobs, err := spec.Extract(body)
+	if err != nil {
+		return nil, err
+	}
+	postings, err := ExtractJSONLD(body, spec.Source, pageURL)
+	if err != nil || len(postings) == 0 {
+		return jobs, nil
+	}
+
+	cards := make(map[string]int, 2*len(jobs))
+	for i, job := range jobs {
+		cards[job.ID] = i
+		if job.URL != "" {
+			cards[canonicalURL(job.URL)] = i
+		}
+	}
+	for _, posting := range postings {
+		if id := spec.nativeID(posting.URL); id != "" {
+			posting.ID = JobID(spec.Source, id, posting)
+		}
+		i, ok := cards[posting.ID]
+		if !ok && posting.URL != pageURL {
+			i, ok = cards[canonicalURL(posting.URL)]
+		}
+		if ok {
+			jobs[i] = posting
+		} else {
+			jobs = append(jobs, posting)
+		}
+	}
+	return jobs, nil
+}
+
+// findJobPostings walks a decoded JSON-LD value and returns every object
+// typed JobPosting.
+func findJobPostings(v any) []map[string]any {
+	var found []map[string]any
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			found = append(found, findJobPostings(item)...)
+		}
+	case map[string]any:
+		if hasType(v["@type"], "JobPosting") {
+			return []map[string]any{v}
+		}
+		for _, key := range []string{"@graph", "itemListElement", "item", "mainEntity"} {
+			if child, ok := v[key]; ok {
+				found = append(found, findJobPostings(child)...)
+			}
+		}
+	}
+	return found
+}
+
+// hasType reports whether a JSON-LD @type, a string or a list, includes want.
+func hasType(t any, want string) bool {
+	switch t := t.(type) {
+	case string:
+		return t == want || t == "http://schema.org/"+want || t == "https://schema.org/"+want
+	case []any:
+		for _, item := range t {
+			if hasType(item, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jobFromPosting(p map[string]any, source string, base *url.URL) models.Job {
+	job := models.Job{
+		Source:         source,
+		Title:          cleanText(firstNonEmpty(jsonString(p["title"]), jsonString(p["name"]))),
+		Company:        cleanText(jsonName(p["hiringOrganization"])),
+		Location:       jsonLocation(p),
+		Description:    htmlToText(jsonString(p["description"])),
+		EmploymentType: jsonEmploymentType(p["employmentType"]),
+		PostedDate:     jsonDate(jsonString(p["datePosted"])),
+	}
+
+	if u := jsonString(p["url"]); u != "" {
+		if ref, err := url.Parse(u); err == nil && base != nil {
+			u = base.ResolveReference(ref).String()
+		}
+		job.URL = u
+	} else if base != nil {
+		job.URL = base.String()
+	}
+
+	setSalary(&job, p["baseSalary"])
+
+	// A posting's identifier is only unique within its hiring organization.
+	var nativeID string
+	if id := jsonString(p["identifier"]); id != "" {
+		nativeID = normalizeKey(job.Company) + "-" + id
+	}
+	job.ID = JobID(source, nativeID, job)
+	return job
+}
+
+// jsonString returns v if it is a string, or its @value (or, for a
+// PropertyValue, its value) if it is an object.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		if s := jsonString(v["@value"]); s != "" {
+			return s
+		}
+		return jsonString(v["value"])
+	}
+	return ""
+}
+
+// jsonName reads an Organization, which sites give as an object or a plain string.
+func jsonName(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		return jsonString(v["name"])
+	case []any:
+		if len(v) > 0 {
+			return jsonName(v[0])
+		}
+	}
+	return jsonString(v)
+}
+
+// jsonLocation joins the posting's jobLocation places, and marks remote
+// postings as such.
+func jsonLocation(p map[string]any) string {
+	var places []string
+	var add func(v any)
+	add = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				add(item)
+			}
+		case map[string]any:
+			if addr, ok := v["address"]; ok {
+				add(addr)
+				return
+			}
+			var parts []string
+			for _, key := range []string{"addressLocality", "addressRegion", "addressCountry"} {
+				if part := jsonName(v[key]); part != "" {
+					parts = append(parts, part)
+				}
+			}
+			if place := strings.Join(parts, ", "); place != "" {
+				places = append(places, place)
+			}
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				places = append(places, v)
+			}
+		}
+	}
+	add(p["jobLocation"])
+
+	location := strings.Join(places, "; ")
+	if strings.EqualFold(jsonString(p["jobLocationType"]), "TELECOMMUTE") {
+		if location == "" {
+			return "Remote"
+		}
+		return "Remote (" + location + ")"
+	}
+	return location
+}
+
+func jsonEmploymentType(v any) string {
+	var types []string
+	switch v := v.(type) {
+	case string:
+		types = append(types, v)
+	case []any:
+		for _, item := range v {
+			if s := jsonString(item); s != "" {
+				types = append(types, s)
+			}
+		}
+	}
+	for i, t := range types {
+		types[i] = normalizeEmploymentType(t)
+	}
+	return strings.Join(types, ", ")
+}
+
+// normalizeEmploymentType turns "FULL_TIME", "Full-time" and "full time"
+// into "full_time".
+func normalizeEmploymentType(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "_", " ", "_").Replace(cleanText(s)))
+}
+
+// jsonDate reads datePosted, which is ISO 8601 but not always with a zone.
+func jsonDate(s string) time.Time {
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return parsePostedDate(s)
+}
+
+// setSalary fills the salary fields from a MonetaryAmount.
+func setSalary(job *models.Job, v any) {
+	amount, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	job.SalaryCurrency = jsonString(amount["currency"])
+
+	value := amount["value"]
+	unit := jsonString(amount["unitText"])
+	if q, ok := value.(map[string]any); ok {
+		if unit == "" {
+			unit = jsonString(q["unitText"])
+		}
+		job.SalaryMin = jsonNumber(q["minValue"])
+		job.SalaryMax = jsonNumber(q["maxValue"])
+		if n := jsonNumber(q["value"]); n > 0 && job.SalaryMin == 0 && job.SalaryMax == 0 {
+			job.SalaryMin, job.SalaryMax = n, n
+		}
+	} else if n := jsonNumber(value); n > 0 {
+		job.SalaryMin, job.SalaryMax = n, n
+	}
+	if job.SalaryMax == 0 {
+		job.SalaryMax = job.SalaryMin
+	}
+	job.SalaryPeriod = strings.ToLower(unit)
+
+	if job.SalaryMin == 0 {
+		return
+	}
+	salary := formatAmount(job.SalaryMin)
+	if job.SalaryMax != job.SalaryMin {
+		salary += "-" + formatAmount(job.SalaryMax)
+	}
+	if job.SalaryCurrency != "" {
+		salary = job.SalaryCurrency + " " + salary
+	}
+	if job.SalaryPeriod != "" {
+		salary += " per " + job.SalaryPeriod
+	}
+	job.Salary = salary
+}
+
+func jsonNumber(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		n, _ := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+		return n
+	}
+	return 0
+}
+
+// formatAmount writes a salary figure with thousands separators.
+func formatAmount(n float64) string {
+	s := strconv.FormatFloat(n, 'f', -1, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString("." + frac)
+	}
+	return b.String()
+}
+
+// htmlToText turns a description, which JobPosting allows to be HTML, into
+// plain text with one line per block.
+func htmlToText(s string) string {
+	if s == "" {
+		return ""
+	}
+	// Some sites entity-encode the HTML, so it takes two passes to get
+	// down to the text.
+	for range 2 {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+		if err != nil {
+			break
+		}
+		s = blockText(doc.Selection)
+		if !strings.Contains(s, "<") {
+			break
+		}
+	}
+	return normalizeLines(s)
+}
+
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
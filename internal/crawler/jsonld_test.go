@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/jsonld/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestExtractJSONLD(t *testing.T) {
+	jobs, err := ExtractJSONLD(readTestdata(t, "posting.html"), "Careers", "https://boards.acme-robotics.example/jobs?page=2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Expected 1 job, got %d", len(jobs))
+	}
+
+	job := jobs[0]
+	checks := map[string][2]string{
+		"title":           {job.Title, "Senior Platform Engineer"},
+		"company":         {job.Company, "Acme Robotics"},
+		"location":        {job.Location, "Remote (Austin, TX, US; Denver, CO, US)"},
+		"url":             {job.URL, "https://boards.acme-robotics.example/jobs/4012345"},
+		"description":     {job.Description, "We are looking for a Platform Engineer to run our Kubernetes fleet.\nGo\nTerraform"},
+		"employment type": {job.EmploymentType, "full_time, contractor"},
+		"salary":          {job.Salary, "USD 150,000-185,000 per year"},
+		"source":          {job.Source, "Careers"},
+	}
+	for field, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("Expected %s %q, got %q", field, c[1], c[0])
+		}
+	}
+	if job.SalaryMin != 150000 || job.SalaryMax != 185000 || job.SalaryCurrency != "USD" || job.SalaryPeriod != "year" {
+		t.Errorf("Expected structured salary USD 150000-185000/year, got %v-%v %s/%s",
+			job.SalaryMin, job.SalaryMax, job.SalaryCurrency, job.SalaryPeriod)
+	}
+	if want := time.Date(2025, 4, 9, 8, 15, 0, 0, time.UTC); !job.PostedDate.Equal(want) {
+		t.Errorf("Expected posted date %v, got %v", want, job.PostedDate)
+	}
+}
+
+func TestExtractJSONLDGraph(t *testing.T) {
+	jobs, err := ExtractJSONLD(readTestdata(t, "listing.html"), "Careers", "https://careers.globex.example/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs from the ItemList in @graph, got %d", len(jobs))
+	}
+	if jobs[0].Company != "Globex" || jobs[0].Location != "Berlin, Germany" {
+		t.Errorf("Expected string organization and address to be read, got %q in %q", jobs[0].Company, jobs[0].Location)
+	}
+	if jobs[0].Salary != "EUR 85,000 per year" {
+		t.Errorf("Expected a single-value salary, got %q", jobs[0].Salary)
+	}
+	if jobs[1].Location != "Berlin, DE" {
+		t.Errorf("Expected PostalAddress location, got %q", jobs[1].Location)
+	}
+}
+
+func TestExtractJobsFallsBackToSelectors(t *testing.T) {
+	spec := builtinSelector("LinkedIn")
+
+	// A page with JSON-LD and no cards yields its postings.
+	jobs, err := extractJobs(readTestdata(t, "listing.html"), "https://careers.globex.example/", spec)
+	if err != nil || len(jobs) != 2 || jobs[0].Source != "LinkedIn" {
+		t.Fatalf("Expected 2 JSON-LD jobs tagged LinkedIn, got %v, %v", jobs, err)
+	}
+
+	// One without JSON-LD yields its cards.
+	jobs, err = extractJobs([]byte(fixturePage(t, "linkedin")), baseLinkedInURL, spec)
+	if err != nil || len(jobs) != 2 || jobs[0].Title != "Software Engineer" {
+		t.Fatalf("Expected 2 jobs from the selectors, got %v, %v", jobs, err)
+	}
+}
+
+func TestExtractJobsMergesJSONLD(t *testing.T) {
+	spec := builtinSelector("LinkedIn")
+
+	// A results page with JSON-LD for one featured card, and for a posting
+	// without a card.
+	page := `<script type="application/ld+json">[
+		{"@type": "JobPosting", "title": "Software Engineer", "url": "https://www.linkedin.com/jobs/view/3891234567",
+		 "hiringOrganization": {"name": "Acme Corp"}, "description": "<p>Build things in Go.</p>"},
+		{"@type": "JobPosting", "title": "Site Reliability Engineer", "url": "https://www.linkedin.com/jobs/view/3890000001",
+		 "hiringOrganization": {"name": "Initech"}}
+	]</script>` + fixturePage(t, "linkedin")
+	jobs, err := extractJobs([]byte(page), baseLinkedInURL, spec)
+	if err != nil || len(jobs) != 3 {
+		t.Fatalf("Expected both cards and the extra posting, got %v, %v", jobs, err)
+	}
+	if jobs[0].ID != "linkedin:3891234567" || jobs[0].Description != "Build things in Go." {
+		t.Errorf("Expected the featured card to take its posting's fields, got %+v", jobs[0])
+	}
+	if jobs[1].Title != "Senior Backend Engineer (Go)" || jobs[2].ID != "linkedin:3890000001" {
+		t.Errorf("Expected the other card, then the extra posting, got %q and %q", jobs[1].Title, jobs[2].ID)
+	}
+}
+
+func TestCareersCrawler(t *testing.T) {
+	if lookupFactory("Careers")(SourceOptions{}) != nil {
+		t.Fatal("Expected the Careers source to be skipped without URLs")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/acme":
+			w.Write(readTestdata(t, "posting.html"))
+		case "/globex":
+			w.Write(readTestdata(t, "listing.html"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := lookupFactory("Careers")(SourceOptions{
+		Config: SourceConfig{URLs: []string{server.URL + "/acme", server.URL + "/missing", server.URL + "/globex"}},
+		Client: server.Client(),
+	})
+
+	jobs, err := source.Crawl(context.Background(), JobSearchParams{Title: "engineer"})
+	if err != nil {
+		t.Fatalf("Expected a failing page to be skipped, got %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("Expected the 2 engineering jobs, got %+v", jobs)
+	}
+
+	jobs, err = source.Crawl(context.Background(), JobSearchParams{Title: "engineer", Location: "berlin"})
+	if err != nil || len(jobs) != 1 || jobs[0].Title != "Backend Engineer (Go)" {
+		t.Errorf("Expected only the Berlin engineering job, got %+v, %v", jobs, err)
+	}
+
+	broken := lookupFactory("Careers")(SourceOptions{
+		Config: SourceConfig{URLs: []string{server.URL + "/missing"}},
+		Client: server.Client(),
+	})
+	if _, err := broken.Crawl(context.Background(), JobSearchParams{}); err == nil {
+		t.Error("Expected an error when no page could be read")
+	}
+}
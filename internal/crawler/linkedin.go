@@ -1,149 +1,108 @@
 package crawler
 
 import (
-	"bytes"
 	"context"
-	"fmt"
-	"golang.org/x/net/html"
-	"io"
 	"net/http"
 	"net/url"
-	"strings"
-	"time"
+	"strconv"
+
 	"job-hunter/internal/logger"
 	"job-hunter/internal/models"
 )
 
 var baseLinkedInURL = "https://www.linkedin.com/jobs-guest/jobs/api/seeMoreJobPostings/search"
 
+// linkedInPageSize is how many cards the guest API returns per request.
+const linkedInPageSize = 25
+
+func init() {
+	Register("LinkedIn", func(opts SourceOptions) Source { return NewLinkedInCrawler(opts) })
+}
+
 type LinkedInCrawler struct {
-	client *http.Client
+	client    *http.Client
+	config    SourceConfig
+	selectors *SelectorSpec
 }
 
-func NewLinkedInCrawler() *LinkedInCrawler {
+func NewLinkedInCrawler(opts SourceOptions) *LinkedInCrawler {
 	return &LinkedInCrawler{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:    opts.client("LinkedIn"),
+		config:    opts.Config,
+		selectors: opts.selectors("LinkedIn"),
+	}
+}
+
+func (c *LinkedInCrawler) Name() string {
+	return "LinkedIn"
+}
+
+func (c *LinkedInCrawler) Capabilities() Capabilities {
+	return Capabilities{
+		Location:   true,
+		Pagination: true,
+		Filters:    FilterKeywords | FilterExcludeKeywords | FilterWorkplace | FilterEmploymentType | FilterExperienceLevel | FilterPostedWithin | FilterRadius,
 	}
 }
 
 func (c *LinkedInCrawler) Crawl(ctx context.Context, params JobSearchParams) ([]models.Job, error) {
 	log := logger.Get().With().Str("source", "LinkedIn").Logger()
+
+	jobs, err := paginate(ctx, c.Name(), c.config, func(ctx context.Context, page int) ([]models.Job, error) {
+		return c.fetchPage(ctx, params, page)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Int("job_count", len(jobs)).Msg("Completed LinkedIn crawl")
+	return jobs, nil
+}
+
+func (c *LinkedInCrawler) fetchPage(ctx context.Context, params JobSearchParams, page int) ([]models.Job, error) {
 	baseURL := baseLinkedInURL
 	urlParams := url.Values{}
-	urlParams.Add("keywords", params.Title)
+	urlParams.Add("keywords", params.query(func(term string) string { return "NOT " + term }))
 	urlParams.Add("location", params.Location)
-	urlParams.Add("start", "0")
-	
-	log.Info().Str("url", baseURL+"?"+urlParams.Encode()).Msg("Creating request")
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"?"+urlParams.Encode(), nil)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to create request")
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
-	
-	log.Debug().Msg("Sending request")
-	resp, err := c.client.Do(req)
+	urlParams.Add("start", strconv.Itoa(page*linkedInPageSize))
+	addLinkedInFilters(urlParams, params)
+
+	pageURL := baseURL + "?" + urlParams.Encode()
+	body, err := getPage(ctx, c.client, c.Name(), pageURL, nil)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to send request")
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		log.Error().Int("status_code", resp.StatusCode).Msg("Unexpected status code")
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-	log.Debug().Int("status_code", resp.StatusCode).Msg("Request successful")
+	return extractJobs(body, pageURL, c.selectors)
+}
 
-	var jobs []models.Job
+// LinkedIn's codes for the search filters.
+var (
+	linkedInWorkplaces      = map[string]string{WorkplaceOnsite: "1", WorkplaceRemote: "2", WorkplaceHybrid: "3"}
+	linkedInEmploymentTypes = map[string]string{EmploymentFullTime: "F", EmploymentPartTime: "P", EmploymentContractor: "C", EmploymentTemporary: "T", EmploymentIntern: "I"}
+	linkedInExperience      = map[string]string{ExperienceInternship: "1", ExperienceEntry: "2", ExperienceMid: "3", ExperienceSenior: "4", ExperienceDirector: "5", ExperienceExecutive: "6"}
+)
 
-	// Read all response bytes
-	log.Debug().Msg("Reading response body")
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to read response body")
-		return nil, fmt.Errorf("reading response body: %w", err)
+// addLinkedInFilters adds the filters of params to a search's query.
+func addLinkedInFilters(urlParams url.Values, params JobSearchParams) {
+	if code, ok := linkedInWorkplaces[params.Workplace]; ok {
+		urlParams.Add("f_WT", code)
 	}
-
-	// Parse HTML response
-	log.Debug().Msg("Parsing HTML response")
-	doc, err := html.Parse(bytes.NewReader(body))
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to parse HTML")
-		return nil, fmt.Errorf("parsing HTML: %w", err)
+	if code, ok := linkedInEmploymentTypes[params.EmploymentType]; ok {
+		urlParams.Add("f_JT", code)
 	}
-
-	// Find job listings
-	var f func(*html.Node)
-	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "div" {
-			// Check if this is a job card
-			isJobCard := false
-			for _, a := range n.Attr {
-				if a.Key == "class" && strings.Contains(a.Val, "job-search-card") {
-					isJobCard = true
-					break
-				}
-			}
-
-			if isJobCard {
-				// Extract job details
-				job := models.Job{Source: "LinkedIn"}
-
-				// Find title, company, and link
-				var findDetails func(*html.Node)
-				findDetails = func(node *html.Node) {
-					if node.Type == html.ElementNode {
-						switch node.Data {
-						case "h3":
-							// Job title
-							if node.FirstChild != nil {
-								job.Title = node.FirstChild.Data
-							}
-						case "h4":
-							// Company name
-							if node.FirstChild != nil {
-								job.Company = node.FirstChild.Data
-							}
-						case "a":
-							// Job URL
-							for _, a := range node.Attr {
-								if a.Key == "href" {
-									job.URL = a.Val
-									break
-								}
-							}
-						}
-					}
-					for c := node.FirstChild; c != nil; c = c.NextSibling {
-						findDetails(c)
-					}
-				}
-
-				findDetails(n)
-				
-				// Generate a unique ID
-				job.ID = fmt.Sprintf("linkedin-%s-%s", url.QueryEscape(job.Title), url.QueryEscape(job.Company))
-				
-				// Add job if we have the minimum required fields
-				if job.Title != "" && job.Company != "" {
-					log.Debug().Str("title", job.Title).Str("company", job.Company).Str("location", job.Location).Msg("Found job")
-					jobs = append(jobs, job)
-				}
-			}
-		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			f(c)
-		}
+	if code, ok := linkedInExperience[params.ExperienceLevel]; ok {
+		urlParams.Add("f_E", code)
+	}
+	if params.PostedWithin > 0 {
+		urlParams.Add("f_TPR", "r"+strconv.Itoa(int(params.PostedWithin.Seconds())))
+	}
+	if params.Radius > 0 {
+		urlParams.Add("distance", strconv.Itoa(params.Radius))
+	}
+	// Salaries come in $20,000 steps from $40,000 (1) to $200,000 (9); the
+	// highest step below the minimum only narrows the search, so the
+	// minimum is still checked on the jobs.
+	if step := int(params.MinSalary/20000) - 1; step >= 1 {
+		urlParams.Add("f_SB2", strconv.Itoa(min(step, 9)))
 	}
-
-	f(doc)
-
-	log.Info().Int("job_count", len(jobs)).Msg("Completed LinkedIn crawl")
-	return jobs, nil
 }
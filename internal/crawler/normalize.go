@@ -0,0 +1,171 @@
+package crawler
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
+	"job-hunter/internal/models"
+)
+
+// normalizeJob cleans up the fields of a scraped job so every source hands
+// out the same shape of data. Text is entity-decoded, NFC-normalized and
+// whitespace-collapsed, the description keeps one line per block, listing
+// badges such as "new" or "Promoted" are dropped from the title and
+// company, and tracking parameters are removed from the URL. It is
+// idempotent.
+func normalizeJob(job *models.Job) {
+	job.Title = stripBadges(normalizeText(job.Title))
+	job.Company = stripBadges(normalizeText(job.Company))
+	job.Location = normalizeText(job.Location)
+	job.Salary = normalizeText(job.Salary)
+	job.Description = normalizeLines(job.Description)
+	job.URL = stripTrackingParams(job.URL)
+
+	// A hashed ID was computed from the raw fields; recompute it so a
+	// badge coming and going doesn't change the job's identity.
+	if strings.Contains(job.ID, ":h-") {
+		job.ID = JobID(job.Source, "", *job)
+	}
+}
+
+// invisibleChars are removed outright; strings.Fields doesn't treat them as
+// space.
+var invisibleChars = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\ufeff", "", "\u00ad", "")
+
+// normalizeText decodes entities left in s (JSON-LD strings often carry
+// them), applies Unicode NFC and collapses whitespace.
+func normalizeText(s string) string {
+	if s == "" {
+		return ""
+	}
+	if strings.Contains(s, "&") {
+		s = html.UnescapeString(s)
+	}
+	return cleanText(invisibleChars.Replace(norm.NFC.String(s)))
+}
+
+// normalizeLines is normalizeText applied line by line, dropping empty lines.
+func normalizeLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = normalizeText(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// leadingBadge matches Indeed's lowercase "new" (a capitalized "New"
+	// is more likely part of the title, as in "New Grad Engineer") and
+	// sponsorship labels.
+	leadingBadge = regexp.MustCompile(`^(?:new|NEW!?|(?i:promoted|sponsored|featured))(?:\s*[-–—:|·•]\s*|\s+)`)
+	// trailingBadge matches labels appended after the title.
+	trailingBadge = regexp.MustCompile(`(?i)(?:\s*[-–—|·•]\s*|\s+)(?:new!?|promoted|sponsored|featured|easy apply|actively recruiting|urgently hiring)$`)
+)
+
+// stripBadges removes listing badges from either end of s.
+func stripBadges(s string) string {
+	for {
+		stripped := trailingBadge.ReplaceAllString(leadingBadge.ReplaceAllString(s, ""), "")
+		if stripped == s || stripped == "" {
+			return s
+		}
+		s = stripped
+	}
+}
+
+// trackingParams are query parameters that only identify the visit, not
+// the job, on any site: ad and mail click IDs and parameters namespaced by
+// the tool that adds them. Parameters starting with "utm_" are removed as
+// well.
+var trackingParams = map[string]bool{
+	"gclid": true, "fbclid": true, "msclkid": true, "mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true,
+	// Applicant tracking systems, also on the careers pages embedding them
+	"gh_src": true, "lever-source": true, "lever-origin": true,
+}
+
+// siteTrackingParams are tracking parameters of one site, keyed by a label
+// of its host names, e.g. "indeed" for www.indeed.com and uk.indeed.com.
+// Elsewhere the same names, such as src or pos, can identify the job or
+// the page, so they are only removed from the site's own URLs.
+var siteTrackingParams = map[string]map[string]bool{
+	"linkedin":  {"trk": true, "trkInfo": true, "refId": true, "trackingId": true, "position": true, "pageNum": true},
+	"indeed":    {"bb": true, "xkcb": true, "vjs": true, "tk": true, "from": true},
+	"glassdoor": {"pos": true, "ao": true, "guid": true, "src": true, "cs": true, "cb": true, "jrtk": true},
+}
+
+// stripTrackingParams removes the tracking parameters from the query of u.
+func stripTrackingParams(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.RawQuery == "" {
+		return u
+	}
+	var siteParams map[string]bool
+	for _, label := range strings.Split(strings.ToLower(parsed.Hostname()), ".") {
+		if params, ok := siteTrackingParams[label]; ok {
+			siteParams = params
+			break
+		}
+	}
+	query := parsed.Query()
+	changed := false
+	for key := range query {
+		if trackingParams[key] || siteParams[key] || strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return u
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// blockTags are the elements that start a new line of text.
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Nav: true,
+	atom.Table: true, atom.Tr: true, atom.Td: true, atom.Th: true, atom.Blockquote: true, atom.Pre: true, atom.Hr: true,
+	atom.Br: true,
+}
+
+// blockText returns the text of sel like Selection.Text, but with a
+// newline around every block-level element so "<li>Go</li><li>SQL</li>"
+// reads "Go\nSQL" rather than "GoSQL". Script and style contents are left
+// out.
+func blockText(sel *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		switch n.Type {
+		case nethtml.TextNode:
+			b.WriteString(n.Data)
+			return
+		case nethtml.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template:
+				return
+			}
+			if blockTags[n.DataAtom] {
+				b.WriteByte('\n')
+				defer b.WriteByte('\n')
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
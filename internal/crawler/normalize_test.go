@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"job-hunter/internal/models"
+)
+
+func TestNormalizeJob(t *testing.T) {
+	job := models.Job{
+		ID:          "indeed:h-0000000000000000",
+		Source:      "Indeed",
+		Title:       "\n      new\n      Cafe\u0301 R&amp;D   Engineer\u200b",
+		Company:     "  Acme Corp \n Promoted",
+		Location:    "Austin,\n   TX",
+		Description: "  First   line \n\n\n Second&nbsp;line ",
+		URL:         "https://www.indeed.com/rc/clk?jk=8f3c1a2b4d5e6f70&bb=Lx0Vc2&utm_source=mail&vjs=3",
+	}
+	normalizeJob(&job)
+
+	checks := map[string][2]string{
+		"title":       {job.Title, "Caf\u00e9 R&D Engineer"},
+		"company":     {job.Company, "Acme Corp"},
+		"location":    {job.Location, "Austin, TX"},
+		"description": {job.Description, "First line\nSecond line"},
+		"url":         {job.URL, "https://www.indeed.com/rc/clk?jk=8f3c1a2b4d5e6f70"},
+	}
+	for field, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("Expected %s %q, got %q", field, c[1], c[0])
+		}
+	}
+	if want := JobID("Indeed", "", job); job.ID != want {
+		t.Errorf("Expected the hashed ID to be recomputed from the clean fields, got %q", job.ID)
+	}
+
+	again := job
+	normalizeJob(&again)
+	if !reflect.DeepEqual(again, job) {
+		t.Errorf("Expected normalizing twice to change nothing, got %+v", again)
+	}
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := map[string]string{
+		"https://www.indeed.com/viewjob?jk=1&tk=abc&from=serp":                        "https://www.indeed.com/viewjob?jk=1",
+		"https://uk.indeed.com/viewjob?jk=1&vjs=3":                                    "https://uk.indeed.com/viewjob?jk=1",
+		"https://www.linkedin.com/jobs/view/42?refId=x&trackingId=y":                  "https://www.linkedin.com/jobs/view/42",
+		"https://www.glassdoor.co.uk/job-listing/x?jl=9&pos=101&ao=1&src=GD_JOB_AD":   "https://www.glassdoor.co.uk/job-listing/x?jl=9",
+		"https://careers.example.com/jobs?src=engineering&pos=3&utm_source=x&gclid=1": "https://careers.example.com/jobs?pos=3&src=engineering",
+		"https://acme.com/careers?gh_jid=12&gh_src=abc&from=2":                        "https://acme.com/careers?from=2&gh_jid=12",
+	}
+	for in, want := range tests {
+		if got := stripTrackingParams(in); got != want {
+			t.Errorf("stripTrackingParams(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStripBadges(t *testing.T) {
+	tests := map[string]string{
+		"new Senior Engineer":          "Senior Engineer",
+		"New Grad Software Engineer":   "New Grad Software Engineer",
+		"Promoted · Platform Engineer": "Platform Engineer",
+		"Data Engineer - Easy Apply":   "Data Engineer",
+		"Backend Engineer new":         "Backend Engineer",
+		"Promoted":                     "Promoted",
+	}
+	for in, want := range tests {
+		if got := stripBadges(in); got != want {
+			t.Errorf("stripBadges(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBlockText(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<h3><span>Senior</span><div>Engineer</div></h3><ul><li>Go</li><li>S<b>QL</b></li></ul><script>var x</script>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cleanText(blockText(doc.Selection)); got != "Senior Engineer Go SQL" {
+		t.Errorf("Expected block elements to be separated, got %q", got)
+	}
+}
+
+func TestSearchJobsNormalizes(t *testing.T) {
+	source := &mockSource{jobs: []models.Job{{ID: "mock:1", Source: "Mock", Title: "\n  Go   Engineer \n", URL: "https://jobs.example.com/1?utm_campaign=x"}}}
+	result, err := NewJobCrawlerWithSources(Config{}, source).SearchJobs(context.Background(), JobSearchParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := result.Jobs[0]; job.Title != "Go Engineer" || job.URL != "https://jobs.example.com/1" {
+		t.Errorf("Expected the job to be normalized before it is returned, got %+v", job)
+	}
+}
@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+)
+
+// Pagination bounds how far a source pages through its results. Zero fields
+// mean "no limit", except MaxPages where zero means a single page.
+type Pagination struct {
+	// MaxPages is the most result pages fetched per search.
+	MaxPages int
+	// MaxJobs stops paging once this many jobs have been collected.
+	MaxJobs int
+	// MaxAge drops jobs posted longer ago than this and stops paging once a
+	// whole page is older than the cutoff.
+	MaxAge time.Duration
+}
+
+// merge returns p with every zero field filled in from defaults.
+func (p Pagination) merge(defaults Pagination) Pagination {
+	if p.MaxPages == 0 {
+		p.MaxPages = defaults.MaxPages
+	}
+	if p.MaxJobs == 0 {
+		p.MaxJobs = defaults.MaxJobs
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = defaults.MaxAge
+	}
+	return p
+}
+
+// timeNow is swapped out by tests that parse relative dates.
+var timeNow = time.Now
+
+// pageFetcher fetches a single page of results. Pages are numbered from 0.
+type pageFetcher func(ctx context.Context, page int) ([]models.Job, error)
+
+// paginate calls fetch for successive pages until the source runs dry, a
+// limit from cfg is hit, or every job on a page is older than the cutoff.
+// Consecutive pages are spaced out by the source's politeness delay.
+//
+// An error on the first page fails the crawl. An error on a later page ends
+// pagination but keeps the jobs already collected, except a
+// *RobotsBlockedError: the site asked not to be crawled that far, and the
+// source must be reported as blocked rather than as having succeeded.
+func paginate(ctx context.Context, source string, cfg SourceConfig, fetch pageFetcher) ([]models.Job, error) {
+	log := logger.Get().With().Str("source", source).Logger()
+
+	maxPages := cfg.Pagination.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	var cutoff time.Time
+	if cfg.Pagination.MaxAge > 0 {
+		cutoff = timeNow().Add(-cfg.Pagination.MaxAge)
+	}
+
+	var jobs []models.Job
+	seen := make(map[string]bool)
+	for page := 0; page < maxPages; page++ {
+		if page > 0 && cfg.Delay > 0 {
+			t := time.NewTimer(cfg.Delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				log.Warn().Err(ctx.Err()).Int("page", page).Msg("Stopped paging early")
+				return jobs, nil
+			}
+		}
+
+		pageJobs, err := fetch(ctx, page)
+		if err != nil {
+			var robotsErr *RobotsBlockedError
+			if page == 0 || errors.As(err, &robotsErr) {
+				return nil, err
+			}
+			log.Warn().Err(err).Int("page", page).Msg("Failed to fetch page, keeping earlier results")
+			return jobs, nil
+		}
+
+		fresh, stale := 0, 0
+		for _, job := range pageJobs {
+			if seen[job.ID] {
+				continue
+			}
+			seen[job.ID] = true
+			fresh++
+
+			if !cutoff.IsZero() && !job.PostedDate.IsZero() && job.PostedDate.Before(cutoff) {
+				stale++
+				continue
+			}
+			jobs = append(jobs, job)
+			if cfg.Pagination.MaxJobs > 0 && len(jobs) >= cfg.Pagination.MaxJobs {
+				log.Debug().Int("page", page).Msg("Reached max jobs")
+				return jobs, nil
+			}
+		}
+
+		log.Debug().Int("page", page).Int("new_jobs", fresh).Int("stale_jobs", stale).Msg("Fetched page")
+		if fresh == 0 {
+			break
+		}
+		if stale == fresh {
+			log.Debug().Int("page", page).Time("cutoff", cutoff).Msg("Whole page is older than cutoff")
+			break
+		}
+	}
+	return jobs, nil
+}
@@ -0,0 +1,304 @@
+package crawler
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"job-hunter/internal/models"
+)
+
+// Workplaces of JobSearchParams.Workplace.
+const (
+	WorkplaceRemote = "remote"
+	WorkplaceHybrid = "hybrid"
+	WorkplaceOnsite = "onsite"
+)
+
+// Values of JobSearchParams.EmploymentType, in the normalized form of
+// models.Job.EmploymentType.
+const (
+	EmploymentFullTime   = "full_time"
+	EmploymentPartTime   = "part_time"
+	EmploymentContractor = "contractor"
+	EmploymentTemporary  = "temporary"
+	EmploymentIntern     = "intern"
+)
+
+// Values of JobSearchParams.ExperienceLevel, from the most junior.
+const (
+	ExperienceInternship = "internship"
+	ExperienceEntry      = "entry"
+	ExperienceMid        = "mid"
+	ExperienceSenior     = "senior"
+	ExperienceDirector   = "director"
+	ExperienceExecutive  = "executive"
+)
+
+var (
+	Workplaces       = []string{WorkplaceRemote, WorkplaceHybrid, WorkplaceOnsite}
+	EmploymentTypes  = []string{EmploymentFullTime, EmploymentPartTime, EmploymentContractor, EmploymentTemporary, EmploymentIntern}
+	ExperienceLevels = []string{ExperienceInternship, ExperienceEntry, ExperienceMid, ExperienceSenior, ExperienceDirector, ExperienceExecutive}
+)
+
+// SearchFilter is a set of the filters of JobSearchParams beyond the title
+// and location.
+type SearchFilter uint
+
+const (
+	FilterKeywords SearchFilter = 1 << iota
+	FilterExcludeKeywords
+	FilterWorkplace
+	FilterEmploymentType
+	FilterExperienceLevel
+	FilterPostedWithin
+	FilterRadius
+	FilterMinSalary
+)
+
+// Validate reports the first field of p that has a value it doesn't know.
+func (p JobSearchParams) Validate() error {
+	enums := []struct {
+		name, value string
+		allowed     []string
+	}{
+		{"workplace", p.Workplace, Workplaces},
+		{"employment type", p.EmploymentType, EmploymentTypes},
+		{"experience level", p.ExperienceLevel, ExperienceLevels},
+	}
+	for _, e := range enums {
+		if e.value != "" && !slices.Contains(e.allowed, e.value) {
+			return fmt.Errorf("unknown %s %q (one of: %s)", e.name, e.value, strings.Join(e.allowed, ", "))
+		}
+	}
+	switch {
+	case p.PostedWithin < 0:
+		return fmt.Errorf("posted within must not be negative")
+	case p.Radius < 0:
+		return fmt.Errorf("radius must not be negative")
+	case p.MinSalary < 0:
+		return fmt.Errorf("minimum salary must not be negative")
+	}
+	return nil
+}
+
+// FilterKey returns the filters of p beyond the title and location in a
+// canonical form, or "" if it has none. Searches with different filters
+// find different jobs, so their histories are kept apart.
+func (p JobSearchParams) FilterKey() string {
+	var parts []string
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, name+"="+value)
+		}
+	}
+	words := func(keywords []string) string {
+		var lower []string
+		for _, k := range keywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+				lower = append(lower, k)
+			}
+		}
+		slices.Sort(lower)
+		return strings.Join(slices.Compact(lower), ",")
+	}
+	add("keywords", words(p.Keywords))
+	add("exclude", words(p.ExcludeKeywords))
+	add("workplace", p.Workplace)
+	add("employment", p.EmploymentType)
+	add("experience", p.ExperienceLevel)
+	if p.PostedWithin > 0 {
+		add("posted", p.PostedWithin.String())
+	}
+	if p.Radius > 0 {
+		add("radius", strconv.Itoa(p.Radius))
+	}
+	if p.MinSalary > 0 {
+		add("salary", strconv.FormatFloat(p.MinSalary, 'f', -1, 64))
+	}
+	if p.Query != nil {
+		add("query", p.Query.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// query returns the title and keywords, with the simple terms of Query, as
+// one search box query, quoting keywords of several words. When not is set,
+// excluded keywords are added in the form it returns, for sites whose
+// search has a syntax for it.
+func (p JobSearchParams) query(not func(term string) string) string {
+	quote := func(k string) string {
+		if strings.ContainsAny(k, " \t") {
+			return strconv.Quote(k)
+		}
+		return k
+	}
+	keywords, exclude := p.Keywords, p.ExcludeKeywords
+	if p.Query != nil {
+		queryInclude, queryExclude := p.Query.Keywords()
+		keywords = append(slices.Clip(keywords), queryInclude...)
+		exclude = append(slices.Clip(exclude), queryExclude...)
+	}
+	terms := []string{strings.TrimSpace(p.Title)}
+	for _, k := range keywords {
+		if k = strings.TrimSpace(k); k != "" {
+			terms = append(terms, quote(k))
+		}
+	}
+	if not != nil {
+		for _, k := range exclude {
+			if k = strings.TrimSpace(k); k != "" {
+				terms = append(terms, not(quote(k)))
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(terms, " "))
+}
+
+// postedWithinDays returns PostedWithin rounded up to whole days, for sites
+// that filter by age in days, or 0 if it isn't set.
+func (p JobSearchParams) postedWithinDays() int {
+	if p.PostedWithin <= 0 {
+		return 0
+	}
+	return int(math.Ceil(p.PostedWithin.Hours() / 24))
+}
+
+// SplitList splits a comma-separated list, such as the -sources or
+// -keywords flag, trimming the items and dropping empty ones.
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// FilterJobs returns the jobs matching params, enforcing every filter that
+// isn't in native, the filters the source already applied, and the query,
+// which no source applies in full. A job is only
+// dropped on what it shows: one whose posted date, salary, workplace,
+// employment type or experience level can't be told is kept. Radius can't
+// be checked without geocoding and is left to the sources. jobs is
+// filtered in place.
+func FilterJobs(jobs []models.Job, params JobSearchParams, native SearchFilter) []models.Job {
+	var cutoff time.Time
+	if params.PostedWithin > 0 && native&FilterPostedWithin == 0 {
+		cutoff = timeNow().Add(-params.PostedWithin)
+	}
+	return slices.DeleteFunc(jobs, func(job models.Job) bool {
+		return !matchesFilters(job, params, native, cutoff)
+	})
+}
+
+func matchesFilters(job models.Job, params JobSearchParams, native SearchFilter, cutoff time.Time) bool {
+	if params.Query != nil && !params.Query.Match(job) {
+		return false
+	}
+	text := strings.ToLower(job.Title + "\n" + job.Company + "\n" + job.Description)
+	if native&FilterKeywords == 0 {
+		for _, k := range params.Keywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" && !strings.Contains(text, k) {
+				return false
+			}
+		}
+	}
+	if native&FilterExcludeKeywords == 0 {
+		for _, k := range params.ExcludeKeywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(text, k) {
+				return false
+			}
+		}
+	}
+	if params.Workplace != "" && native&FilterWorkplace == 0 {
+		if w := jobWorkplace(job); w != "" && w != params.Workplace {
+			return false
+		}
+	}
+	if params.EmploymentType != "" && native&FilterEmploymentType == 0 && job.EmploymentType != "" {
+		if !slices.Contains(strings.Split(job.EmploymentType, ", "), params.EmploymentType) {
+			return false
+		}
+	}
+	if params.ExperienceLevel != "" && native&FilterExperienceLevel == 0 {
+		if level := jobExperienceLevel(job); level != "" && level != params.ExperienceLevel {
+			return false
+		}
+	}
+	if !cutoff.IsZero() && !job.PostedDate.IsZero() && job.PostedDate.Before(cutoff) {
+		return false
+	}
+	if params.MinSalary > 0 && native&FilterMinSalary == 0 {
+		if salary := yearlySalary(job); salary > 0 && salary < params.MinSalary {
+			return false
+		}
+	}
+	return true
+}
+
+// jobWorkplace tells from a job's title and location whether it is remote,
+// hybrid or onsite, or returns "" when the job has no location.
+func jobWorkplace(job models.Job) string {
+	words := strings.FieldsFunc(strings.ToLower(job.Title+" "+job.Location), func(r rune) bool {
+		return !isWordRune(r)
+	})
+	switch {
+	case hasWord(words, "hybrid"):
+		return WorkplaceHybrid
+	case hasWord(words, "remote"):
+		return WorkplaceRemote
+	case job.Location != "":
+		return WorkplaceOnsite
+	}
+	return ""
+}
+
+// titleLevels maps words of a job title to the experience level they give
+// away. Titles without one, which mid-level jobs usually are, tell nothing.
+var titleLevels = map[string]string{
+	"intern": ExperienceInternship, "internship": ExperienceInternship,
+	"junior": ExperienceEntry, "jr": ExperienceEntry, "entry": ExperienceEntry, "graduate": ExperienceEntry, "grad": ExperienceEntry, "trainee": ExperienceEntry, "apprentice": ExperienceEntry,
+	"senior": ExperienceSenior, "sr": ExperienceSenior, "lead": ExperienceSenior, "staff": ExperienceSenior, "principal": ExperienceSenior,
+	"director": ExperienceDirector, "head": ExperienceDirector,
+	"vp": ExperienceExecutive, "vice": ExperienceExecutive, "chief": ExperienceExecutive, "cto": ExperienceExecutive, "cio": ExperienceExecutive,
+}
+
+// jobExperienceLevel guesses a job's experience level from its title, the
+// most senior word winning, or returns "".
+func jobExperienceLevel(job models.Job) string {
+	best := -1
+	for _, word := range strings.FieldsFunc(strings.ToLower(job.Title), func(r rune) bool {
+		return !isWordRune(r)
+	}) {
+		if level, ok := titleLevels[word]; ok {
+			best = max(best, slices.Index(ExperienceLevels, level))
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return ExperienceLevels[best]
+}
+
+// PeriodsPerYear converts salaries paid per period to yearly ones, assuming
+// full-time hours.
+var PeriodsPerYear = map[string]float64{"": 1, "year": 1, "month": 12, "week": 52, "day": 260, "hour": 2080}
+
+// yearlySalary returns the top of a job's salary range per year, or 0 if
+// it isn't known.
+func yearlySalary(job models.Job) float64 {
+	salary := job.SalaryMax
+	if salary == 0 {
+		salary = job.SalaryMin
+	}
+	factor, ok := PeriodsPerYear[job.SalaryPeriod]
+	if !ok {
+		return 0
+	}
+	return salary * factor
+}
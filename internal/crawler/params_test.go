@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"job-hunter/internal/models"
+	"job-hunter/internal/query"
+)
+
+func TestFilterJobs(t *testing.T) {
+	now := time.Date(2025, 4, 14, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	jobs := []models.Job{
+		{ID: "1", Title: "Go Developer", Company: "Acme", Location: "Remote", Description: "Go and Kubernetes", EmploymentType: "full_time"},
+		{ID: "2", Title: "Senior Go Engineer", Company: "Hooli", Location: "Austin, TX (Hybrid)", SalaryMin: 60, SalaryPeriod: "hour"},
+		{ID: "3", Title: "Junior Go Developer", Company: "Initech", Location: "Denver, CO", PostedDate: now.AddDate(0, 0, -10)},
+		{ID: "4", Title: "Go Contractor", Company: "Globex Staffing", EmploymentType: "contractor, temporary", SalaryMax: 90000},
+		{ID: "5", Title: "Go Intern", Company: "Acme", Location: "Remote", PostedDate: now.AddDate(0, 0, -1)},
+	}
+	for _, tt := range []struct {
+		name   string
+		params JobSearchParams
+		native SearchFilter
+		want   []string
+	}{
+		{"no filters", JobSearchParams{}, 0, []string{"1", "2", "3", "4", "5"}},
+		{"keywords in the description", JobSearchParams{Keywords: []string{"kubernetes"}}, 0, []string{"1"}},
+		{"keywords applied by the source", JobSearchParams{Keywords: []string{"kubernetes"}}, FilterKeywords, []string{"1", "2", "3", "4", "5"}},
+		{"excluded company", JobSearchParams{ExcludeKeywords: []string{"staffing"}}, 0, []string{"1", "2", "3", "5"}},
+		{"remote or unknown", JobSearchParams{Workplace: WorkplaceRemote}, 0, []string{"1", "4", "5"}},
+		{"hybrid", JobSearchParams{Workplace: WorkplaceHybrid}, 0, []string{"2", "4"}},
+		{"employment type", JobSearchParams{EmploymentType: EmploymentContractor}, 0, []string{"2", "3", "4", "5"}},
+		{"senior", JobSearchParams{ExperienceLevel: ExperienceSenior}, 0, []string{"1", "2", "4"}},
+		{"entry", JobSearchParams{ExperienceLevel: ExperienceEntry}, 0, []string{"1", "3", "4"}},
+		{"posted within", JobSearchParams{PostedWithin: 7 * 24 * time.Hour}, 0, []string{"1", "2", "4", "5"}},
+		{"hourly salary", JobSearchParams{MinSalary: 100000}, 0, []string{"1", "2", "3", "5"}},
+		{"query, even with native keywords", JobSearchParams{Query: mustParse(t, "title:go* AND NOT (junior OR intern)")}, FilterKeywords, []string{"1", "2", "4"}},
+	} {
+		got := FilterJobs(slices.Clone(jobs), tt.params, tt.native)
+		var ids []string
+		for _, job := range got {
+			ids = append(ids, job.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, ids)
+		}
+	}
+}
+
+func TestJobSearchParams(t *testing.T) {
+	params := JobSearchParams{
+		Title:           "golang",
+		Keywords:        []string{"Kubernetes", " distributed systems "},
+		ExcludeKeywords: []string{"php"},
+		PostedWithin:    36 * time.Hour,
+		Radius:          25,
+	}
+	if err := params.Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	for _, bad := range []JobSearchParams{{Workplace: "office"}, {EmploymentType: "gig"}, {ExperienceLevel: "guru"}, {Radius: -1}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+
+	if got, want := params.query(nil), `golang Kubernetes "distributed systems"`; got != want {
+		t.Errorf("Expected query %q, got %q", want, got)
+	}
+	if got, want := params.query(func(term string) string { return "-" + term }), `golang Kubernetes "distributed systems" -php`; got != want {
+		t.Errorf("Expected query %q, got %q", want, got)
+	}
+	params.Query = mustParse(t, `NOT java "event driven" company:acme`)
+	if got, want := params.query(func(term string) string { return "-" + term }), `golang Kubernetes "distributed systems" "event driven" -php -java`; got != want {
+		t.Errorf("Expected the query's terms to be added, got %q", got)
+	}
+	if len(params.Keywords) != 2 || len(params.ExcludeKeywords) != 1 {
+		t.Errorf("Expected the keywords to be left alone, got %v and %v", params.Keywords, params.ExcludeKeywords)
+	}
+	params.Query = nil
+
+	if days := params.postedWithinDays(); days != 2 {
+		t.Errorf("Expected 36h to round up to 2 days, got %d", days)
+	}
+
+	// The key doesn't depend on keyword order or case.
+	if got, want := params.FilterKey(), "keywords=distributed systems,kubernetes;exclude=php;posted=36h0m0s;radius=25"; got != want {
+		t.Errorf("Expected key %q, got %q", want, got)
+	}
+	if key := (JobSearchParams{Title: "golang"}).FilterKey(); key != "" {
+		t.Errorf("Expected no key without filters, got %q", key)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := SplitList(" https://a.example/jobs, https://b.example/jobs ,,")
+	if want := []string{"https://a.example/jobs", "https://b.example/jobs"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := SplitList(""); got != nil {
+		t.Errorf("Expected no items, got %v", got)
+	}
+}
+
+func TestLinkedInFilters(t *testing.T) {
+	urlParams := url.Values{}
+	addLinkedInFilters(urlParams, JobSearchParams{
+		Workplace:       WorkplaceRemote,
+		EmploymentType:  EmploymentFullTime,
+		ExperienceLevel: ExperienceSenior,
+		PostedWithin:    24 * time.Hour,
+		Radius:          10,
+		MinSalary:       130000,
+	})
+	want := "distance=10&f_E=4&f_JT=F&f_SB2=5&f_TPR=r86400&f_WT=2"
+	if got := urlParams.Encode(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func mustParse(t *testing.T, s string) *query.Query {
+	t.Helper()
+	q, err := query.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what a source handles natively, so callers know
+// which parts of a search they have to take care of themselves.
+type Capabilities struct {
+	// Location reports whether the site filters results by location.
+	Location bool
+	// Pagination reports whether the source can fetch more than one page.
+	Pagination bool
+	// Filters are the search filters the site applies itself. FilterJobs
+	// enforces the others on the jobs the source returns.
+	Filters SearchFilter
+}
+
+// SourceOptions is handed to a SourceFactory when a JobCrawler builds its
+// sources from the registry.
+type SourceOptions struct {
+	// Config is the effective configuration for the source being built.
+	Config SourceConfig
+	// Client is the HTTP client the source should send its requests with.
+	Client *http.Client
+	// Selectors holds the selector specs loaded for this crawler, keyed by
+	// lowercased source name. Sources that scrape HTML look theirs up here.
+	Selectors map[string]*SelectorSpec
+}
+
+// client returns opts.Client, falling back to a client from the default
+// factory for sources built by hand.
+func (opts SourceOptions) client(source string) *http.Client {
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return defaultClientFactory().Client(source)
+}
+
+// selectors returns the source's spec from opts.Selectors, falling back to
+// the built-in spec.
+func (opts SourceOptions) selectors(source string) *SelectorSpec {
+	if spec, ok := opts.Selectors[strings.ToLower(source)]; ok {
+		return spec
+	}
+	return builtinSelector(source)
+}
+
+// SourceFactory builds a source. Returning nil means the source cannot run
+// with the given options (e.g. it is missing required settings) and is
+// skipped.
+type SourceFactory func(opts SourceOptions) Source
+
+var registry struct {
+	mu        sync.Mutex
+	names     []string
+	factories map[string]SourceFactory
+}
+
+// Register makes a source available to NewJobCrawler under name. Sources
+// usually register themselves from an init function, which lets private
+// sources live in their own files without touching this package's core.
+// Registering the same name twice panics.
+func Register(name string, factory SourceFactory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if factory == nil {
+		panic("crawler: Register factory is nil for " + name)
+	}
+	key := strings.ToLower(name)
+	if registry.factories == nil {
+		registry.factories = make(map[string]SourceFactory)
+	}
+	if _, dup := registry.factories[key]; dup {
+		panic("crawler: Register called twice for source " + name)
+	}
+	registry.factories[key] = factory
+	registry.names = append(registry.names, name)
+}
+
+// sourceOrder is the order of the built-in sources in searches and reports.
+// Init functions run in file name order, so registration order alone would
+// change whenever a source file is added or renamed.
+var sourceOrder = []string{"LinkedIn", "Indeed", "Monster", "Glassdoor", "Careers"}
+
+// Registered returns the names of all registered sources: the built-in
+// ones in sourceOrder, then any others in registration order.
+func Registered() []string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	names := append([]string(nil), registry.names...)
+	rank := func(name string) int {
+		if i := slices.Index(sourceOrder, name); i >= 0 {
+			return i
+		}
+		return len(sourceOrder)
+	}
+	slices.SortStableFunc(names, func(a, b string) int { return rank(a) - rank(b) })
+	return names
+}
+
+// enabledSources resolves which registered sources the config asks for.
+// Source names are matched case-insensitively.
+func (c Config) enabledSources() ([]string, error) {
+	registered := Registered()
+	known := make(map[string]string, len(registered))
+	for _, name := range registered {
+		known[strings.ToLower(name)] = name
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range c.DisabledSources {
+		name = strings.TrimSpace(name)
+		if _, ok := known[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("unknown source %q (registered: %s)", name, strings.Join(registered, ", "))
+		}
+		disabled[strings.ToLower(name)] = true
+	}
+
+	names := registered
+	if len(c.EnabledSources) > 0 {
+		names = nil
+		for _, name := range c.EnabledSources {
+			name = strings.TrimSpace(name)
+			canonical, ok := known[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown source %q (registered: %s)", name, strings.Join(registered, ", "))
+			}
+			names = append(names, canonical)
+		}
+	}
+
+	var enabled []string
+	for _, name := range names {
+		if !disabled[strings.ToLower(name)] {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled, nil
+}
+
+func lookupFactory(name string) SourceFactory {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	return registry.factories[strings.ToLower(name)]
+}
@@ -0,0 +1,188 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"job-hunter/internal/models"
+)
+
+// ErrorClass groups source failures into the handful of kinds callers act on.
+type ErrorClass string
+
+const (
+	ErrorClassTimeout    ErrorClass = "timeout"
+	ErrorClassCanceled   ErrorClass = "canceled"
+	ErrorClassHTTPStatus ErrorClass = "http_status"
+	ErrorClassParse      ErrorClass = "parse"
+	ErrorClassNetwork    ErrorClass = "network"
+	ErrorClassCircuit    ErrorClass = "circuit_open"
+	ErrorClassRobots     ErrorClass = "blocked_by_robots"
+	ErrorClassUnknown    ErrorClass = "unknown"
+)
+
+// StatusError is returned by a source when the site answers with a non-200 status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Blocked reports whether the status means the site is refusing to serve us
+// rather than having a transient problem. LinkedIn answers 999 when it
+// decides a client is a bot.
+func (e *StatusError) Blocked() bool {
+	switch e.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, 999:
+		return true
+	}
+	return false
+}
+
+// ParseError wraps a failure to make sense of a response body.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing response: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// SourceStatus describes how a single source fared during a search.
+type SourceStatus struct {
+	Name       string        `json:"name"`
+	JobCount   int           `json:"job_count"`
+	Duration   time.Duration `json:"-"`
+	ErrorClass ErrorClass    `json:"error_class,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Blocked    bool          `json:"blocked"`
+	// FillRates is the fraction of the source's jobs that have each of
+	// TrackedFields set. It is empty when the source found no jobs.
+	FillRates map[string]float64 `json:"fill_rates,omitempty"`
+}
+
+// OK reports whether the source completed without error. A source that
+// completed but found nothing is still OK.
+func (s SourceStatus) OK() bool {
+	return s.ErrorClass == ""
+}
+
+type sourceStatusJSON struct {
+	sourceStatusAlias
+	DurationMS int64 `json:"duration_ms"`
+}
+
+type sourceStatusAlias SourceStatus
+
+func (s SourceStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sourceStatusJSON{sourceStatusAlias(s), s.Duration.Milliseconds()})
+}
+
+func (s *SourceStatus) UnmarshalJSON(data []byte) error {
+	var v sourceStatusJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = SourceStatus(v.sourceStatusAlias)
+	s.Duration = time.Duration(v.DurationMS) * time.Millisecond
+	return nil
+}
+
+// TrackedFields are the job fields whose fill rate is reported per source.
+// A field that suddenly stops being filled usually means a selector no
+// longer matches the site's markup.
+var TrackedFields = []string{"title", "company", "location", "url", "posted_date"}
+
+// FillRates returns the fraction of jobs that have each of TrackedFields set.
+func FillRates(jobs []models.Job) map[string]float64 {
+	if len(jobs) == 0 {
+		return nil
+	}
+	counts := make(map[string]int, len(TrackedFields))
+	for _, j := range jobs {
+		for field, set := range map[string]bool{
+			"title":       j.Title != "",
+			"company":     j.Company != "",
+			"location":    j.Location != "",
+			"url":         j.URL != "",
+			"posted_date": !j.PostedDate.IsZero(),
+		} {
+			if set {
+				counts[field]++
+			}
+		}
+	}
+	rates := make(map[string]float64, len(TrackedFields))
+	for _, field := range TrackedFields {
+		rates[field] = float64(counts[field]) / float64(len(jobs))
+	}
+	return rates
+}
+
+// SearchResult is the outcome of a JobCrawler search: the merged jobs plus
+// a status entry for every source that was asked.
+type SearchResult struct {
+	Jobs    []models.Job   `json:"jobs"`
+	Sources []SourceStatus `json:"sources"`
+}
+
+// Failed returns the statuses of the sources that returned an error.
+func (r *SearchResult) Failed() []SourceStatus {
+	var failed []SourceStatus
+	for _, s := range r.Sources {
+		if !s.OK() {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// classifyError fills in the error fields of status from err.
+func classifyError(status *SourceStatus, err error) {
+	status.Error = err.Error()
+
+	var (
+		statusErr  *StatusError
+		parseErr   *ParseError
+		circuitErr *CircuitOpenError
+		robotsErr  *RobotsBlockedError
+		netErr     net.Error
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		status.ErrorClass = ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		status.ErrorClass = ErrorClassCanceled
+	case errors.As(err, &statusErr):
+		status.ErrorClass = ErrorClassHTTPStatus
+		status.StatusCode = statusErr.StatusCode
+		status.Blocked = statusErr.Blocked()
+	case errors.As(err, &parseErr):
+		status.ErrorClass = ErrorClassParse
+	case errors.As(err, &circuitErr):
+		status.ErrorClass = ErrorClassCircuit
+	case errors.As(err, &robotsErr):
+		status.ErrorClass = ErrorClassRobots
+		status.Blocked = true
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			status.ErrorClass = ErrorClassTimeout
+		} else {
+			status.ErrorClass = ErrorClassNetwork
+		}
+	default:
+		status.ErrorClass = ErrorClassUnknown
+	}
+}
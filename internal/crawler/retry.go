@@ -0,0 +1,284 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"job-hunter/internal/logger"
+)
+
+// RetryPolicy configures how RetryTransport retries failed requests.
+type RetryPolicy struct {
+	// MaxRetries is the number of extra attempts made for one request.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles with
+	// every further attempt, with jitter, up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After asking for longer than this
+	// is treated as "give up" rather than waited out.
+	MaxDelay time.Duration
+	// HostBudget is the number of retries allowed per host within
+	// BudgetWindow, so a struggling site isn't hit with a retry storm.
+	HostBudget   int
+	BudgetWindow time.Duration
+	// BreakerThreshold is the number of consecutive failed requests after
+	// which a host's circuit opens and requests fail fast for
+	// BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used by the crawlers.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         20 * time.Second,
+		HostBudget:       20,
+		BudgetWindow:     time.Minute,
+		BreakerThreshold: 5,
+		BreakerCooldown:  2 * time.Minute,
+	}
+}
+
+// CircuitOpenError is returned without contacting the host while its
+// circuit breaker is open.
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// RetryTransport is an http.RoundTripper that retries transient failures
+// (network errors, 429 and 5xx responses) with jittered exponential backoff,
+// honours Retry-After, and keeps a retry budget and circuit breaker per host.
+type RetryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	retries   []time.Time // retry timestamps inside the budget window
+	failures  int         // consecutive failed requests
+	openUntil time.Time
+}
+
+// NewRetryTransport wraps base, or http.DefaultTransport when base is nil.
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{
+		base:   base,
+		policy: policy,
+		hosts:  make(map[string]*hostState),
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log := logger.Get().With().Str("host", req.URL.Host).Logger()
+	host := req.URL.Host
+
+	if err := t.checkCircuit(host); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("retrying %s: request body cannot be replayed", req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if !retryable(req.Context(), resp, err) {
+			t.recordOutcome(req.Context(), host, err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+			return resp, err
+		}
+
+		delay, ok := t.nextDelay(resp, attempt)
+		if !ok || !fitsDeadline(req.Context(), delay) || attempt >= t.policy.MaxRetries || !t.takeBudget(host) {
+			t.recordOutcome(req.Context(), host, false)
+			return resp, err
+		}
+
+		if err != nil {
+			log.Debug().Err(err).Int("attempt", attempt+1).Dur("backoff", delay).Msg("Retrying request")
+		} else {
+			log.Debug().Int("status_code", resp.StatusCode).Int("attempt", attempt+1).Dur("backoff", delay).Msg("Retrying request")
+			// Drain so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryable reports whether the outcome of an attempt is worth retrying.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// The caller gave up; retrying would only fail the same way.
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// nextDelay returns how long to wait before the next attempt. It prefers the
+// server's Retry-After and otherwise uses exponential backoff with jitter.
+// ok is false when the server asks for a longer wait than MaxDelay.
+func (t *RetryTransport) nextDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp != nil {
+		if d, found := parseRetryAfter(resp.Header.Get("Retry-After")); found {
+			if t.policy.MaxDelay > 0 && d > t.policy.MaxDelay {
+				return 0, false
+			}
+			return d, true
+		}
+	}
+
+	return t.policy.Backoff(attempt), true
+}
+
+// Backoff returns the wait before the retry following attempt, counted
+// from 0: BaseDelay doubled for each earlier attempt, capped at MaxDelay,
+// with jitter.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	// Equal jitter: keep half the backoff, randomise the rest.
+	if half := d / 2; half > 0 {
+		d = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return d
+}
+
+// fitsDeadline reports whether a retry after delay would still start before
+// the request's deadline, which includes http.Client.Timeout. When it
+// wouldn't, the last response is returned rather than a timeout.
+func fitsDeadline(ctx context.Context, delay time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > delay
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func (t *RetryTransport) host(name string) *hostState {
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &hostState{}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+func (t *RetryTransport) checkCircuit(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.host(name)
+	if time.Now().Before(h.openUntil) {
+		return &CircuitOpenError{Host: name, Until: h.openUntil}
+	}
+	return nil
+}
+
+// takeBudget consumes one retry from the host's budget, if any is left.
+func (t *RetryTransport) takeBudget(name string) bool {
+	if t.policy.HostBudget <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.host(name)
+
+	now := time.Now()
+	kept := h.retries[:0]
+	for _, at := range h.retries {
+		if now.Sub(at) < t.policy.BudgetWindow {
+			kept = append(kept, at)
+		}
+	}
+	h.retries = kept
+	if len(h.retries) >= t.policy.HostBudget {
+		log := logger.Get()
+		log.Warn().Str("host", name).Msg("Retry budget exhausted")
+		return false
+	}
+	h.retries = append(h.retries, now)
+	return true
+}
+
+// recordOutcome feeds the final outcome of a request into the host's
+// circuit breaker. A success closes the circuit; enough consecutive
+// failures open it. Requests the caller canceled or timed out say nothing
+// about the host and aren't counted.
+func (t *RetryTransport) recordOutcome(ctx context.Context, name string, success bool) {
+	if ctx.Err() != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.host(name)
+
+	if success {
+		h.failures = 0
+		h.openUntil = time.Time{}
+		return
+	}
+	h.failures++
+	if t.policy.BreakerThreshold > 0 && h.failures >= t.policy.BreakerThreshold {
+		h.openUntil = time.Now().Add(t.policy.BreakerCooldown)
+		log := logger.Get()
+		log.Warn().Str("host", name).Time("until", h.openUntil).Msg("Circuit breaker opened")
+	}
+}
@@ -0,0 +1,235 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:       3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         50 * time.Millisecond,
+		HostBudget:       100,
+		BudgetWindow:     time.Minute,
+		BreakerThreshold: 100,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+func TestRetryTransportRetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetryPolicy())}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryTransportDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, testRetryPolicy())}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestRetryTransportHonorsRetryAfter(t *testing.T) {
+	var calls int32
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < time.Second {
+			t.Errorf("Expected to wait at least 1s before retrying, waited %v", waited)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("Expected 200 after 2 attempts, got %d after %d", resp.StatusCode, calls)
+	}
+
+	// A Retry-After beyond MaxDelay is not waited out.
+	calls = 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls != 1 {
+		t.Errorf("Expected to give up on 429 after 1 attempt, got %d after %d", resp.StatusCode, calls)
+	}
+}
+
+func TestRetryTransportHostBudget(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.HostBudget = 2
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Two requests, but only two retries in total are allowed for the host.
+	if calls != 4 {
+		t.Errorf("Expected 4 attempts, got %d", calls)
+	}
+}
+
+func TestRetryTransportCircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxRetries = 0
+	policy.BreakerThreshold = 2
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(server.URL)
+	var circuitErr *CircuitOpenError
+	if !errors.As(err, &circuitErr) {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the open circuit to fail fast, server saw %d calls", calls)
+	}
+
+	status := SourceStatus{}
+	classifyError(&status, err)
+	if status.ErrorClass != ErrorClassCircuit {
+		t.Errorf("Expected error class %q, got %q", ErrorClassCircuit, status.ErrorClass)
+	}
+}
+
+func TestRetryTransportBackoffPastTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	client := &http.Client{Timeout: 500 * time.Millisecond, Transport: NewRetryTransport(nil, policy)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the 429 rather than a timeout, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls != 1 {
+		t.Errorf("Expected to give up on 429 after 1 attempt, got %d after %d", resp.StatusCode, calls)
+	}
+}
+
+func TestRetryTransportCancelDoesNotTripBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	policy.BreakerThreshold = 2
+	client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+
+	// Both requests are canceled while waiting to retry.
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the request to be canceled, got %v", err)
+		}
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the circuit to stay closed, got %v", err)
+	}
+	resp.Body.Close()
+}
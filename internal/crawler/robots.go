@@ -0,0 +1,315 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"job-hunter/internal/logger"
+)
+
+// DefaultRobotsAgent is the product token matched against the User-agent
+// lines of robots.txt files.
+const DefaultRobotsAgent = "job-hunter"
+
+// robotsUserAgent is the User-Agent sent while obeying robots.txt, so sites
+// can tell which rules the crawler follows: the agent's token, a version
+// and, when given, a URL to learn about it or reach its operator.
+func robotsUserAgent(agent, contact string) string {
+	if agent == "" {
+		agent = DefaultRobotsAgent
+	}
+	ua := agent + "/1.0"
+	if contact != "" {
+		ua += " (+" + contact + ")"
+	}
+	return ua
+}
+
+const (
+	// robotsTTL is how long a fetched robots.txt is trusted before refetching.
+	robotsTTL = 24 * time.Hour
+	// robotsErrorTTL is how long an unreachable robots.txt keeps the site
+	// disallowed before we try again.
+	robotsErrorTTL = 10 * time.Minute
+)
+
+// RobotsBlockedError is returned instead of fetching a URL that the site's
+// robots.txt disallows for our agent.
+type RobotsBlockedError struct {
+	URL string
+}
+
+func (e *RobotsBlockedError) Error() string {
+	return fmt.Sprintf("blocked by robots.txt: %s", e.URL)
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsGroup holds the rules that apply to one set of user agents.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsPolicy is the parsed robots.txt of one host, reduced to the group
+// that applies to our agent.
+type robotsPolicy struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	expires    time.Time
+}
+
+// disallowAll is the policy for a site whose robots.txt can't be fetched.
+func disallowAll() *robotsPolicy {
+	return &robotsPolicy{rules: []robotsRule{{allow: false, pattern: "/"}}}
+}
+
+// parseRobots parses a robots.txt body and picks the group for agent: the
+// group naming the agent if there is one, the "*" group otherwise.
+func parseRobots(r io.Reader, agent string) *robotsPolicy {
+	var (
+		groups  []*robotsGroup
+		current *robotsGroup
+		inRules bool // a rule line has been seen since the last User-agent line
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// An empty Disallow allows everything and adds no rule.
+			if value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				current.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var wildcard *robotsGroup
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+			} else if a != "" && strings.Contains(agent, a) {
+				return &robotsPolicy{rules: g.rules, crawlDelay: g.crawlDelay}
+			}
+		}
+	}
+	if wildcard != nil {
+		return &robotsPolicy{rules: wildcard.rules, crawlDelay: wildcard.crawlDelay}
+	}
+	return &robotsPolicy{}
+}
+
+// allowed reports whether path (including any query string) may be fetched.
+// The longest matching rule wins, and Allow wins a tie.
+func (p *robotsPolicy) allowed(path string) bool {
+	best, allow := -1, true
+	for _, r := range p.rules {
+		if !robotsMatch(r.pattern, path) {
+			continue
+		}
+		if n := len(r.pattern); n > best || (n == best && r.allow) {
+			best, allow = n, r.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a robots.txt path pattern, which may use "*" for any
+// run of characters and a trailing "$" to anchor the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	if !anchored {
+		return true
+	}
+	// With a trailing "*" before "$" anything may follow; otherwise the
+	// last literal part has to end the path.
+	last := parts[len(parts)-1]
+	return last == "" || strings.HasSuffix(path, last)
+}
+
+// robotsTransport refuses requests that the host's robots.txt disallows and
+// spaces out requests according to its Crawl-delay.
+type robotsTransport struct {
+	base      http.RoundTripper
+	agent     string
+	userAgent string // Sent when fetching robots.txt
+
+	mu       sync.Mutex
+	policies map[string]*robotsPolicy
+	next     map[string]time.Time // earliest time the host may be hit again
+}
+
+func newRobotsTransport(base http.RoundTripper, agent, userAgent string) *robotsTransport {
+	if agent == "" {
+		agent = DefaultRobotsAgent
+	}
+	return &robotsTransport{
+		base:      base,
+		agent:     agent,
+		userAgent: userAgent,
+		policies:  make(map[string]*robotsPolicy),
+		next:      make(map[string]time.Time),
+	}
+}
+
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.policy(req)
+	if !policy.allowed(req.URL.RequestURI()) {
+		log := logger.Get()
+		log.Warn().Str("source", sourceFromContext(req.Context())).Str("url", req.URL.String()).Msg("Blocked by robots.txt")
+		return nil, &RobotsBlockedError{URL: req.URL.String()}
+	}
+	if err := t.wait(req.Context(), req.URL.Host, policy.crawlDelay); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// wait honours the host's Crawl-delay.
+func (t *robotsTransport) wait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	start := now
+	if next := t.next[host]; next.After(now) {
+		start = next
+	}
+	t.next[host] = start.Add(delay)
+	t.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// policy returns the cached robots policy for the request's host, fetching
+// robots.txt when it is missing or stale.
+func (t *robotsTransport) policy(req *http.Request) *robotsPolicy {
+	key := req.URL.Scheme + "://" + req.URL.Host
+
+	t.mu.Lock()
+	p, ok := t.policies[key]
+	t.mu.Unlock()
+	if ok && time.Now().Before(p.expires) {
+		return p
+	}
+
+	p = t.fetch(req.Context(), key)
+	if req.Context().Err() != nil {
+		// The caller gave up mid-fetch; don't remember a bogus failure.
+		return p
+	}
+	t.mu.Lock()
+	t.policies[key] = p
+	t.mu.Unlock()
+	return p
+}
+
+// fetch downloads and parses robots.txt. Following RFC 9309, a missing file
+// (4xx) allows everything while an unreachable one (5xx or network error)
+// disallows everything.
+func (t *robotsTransport) fetch(ctx context.Context, origin string) *robotsPolicy {
+	log := logger.Get().With().Str("origin", origin).Logger()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		p := disallowAll()
+		p.expires = time.Now().Add(robotsErrorTTL)
+		return p
+	}
+	req.Header.Set("User-Agent", t.userAgent)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch robots.txt, treating site as disallowed")
+		p := disallowAll()
+		p.expires = time.Now().Add(robotsErrorTTL)
+		return p
+	}
+	defer resp.Body.Close()
+
+	var p *robotsPolicy
+	switch {
+	case resp.StatusCode >= 500:
+		log.Warn().Int("status_code", resp.StatusCode).Msg("robots.txt unavailable, treating site as disallowed")
+		p = disallowAll()
+		p.expires = time.Now().Add(robotsErrorTTL)
+		return p
+	case resp.StatusCode >= 400:
+		log.Debug().Int("status_code", resp.StatusCode).Msg("No robots.txt, everything allowed")
+		p = &robotsPolicy{}
+	default:
+		// RFC 9309 asks crawlers to parse at least 500 KiB.
+		p = parseRobots(io.LimitReader(resp.Body, 512<<10), t.agent)
+		log.Debug().Int("rules", len(p.rules)).Dur("crawl_delay", p.crawlDelay).Msg("Loaded robots.txt")
+	}
+	p.expires = time.Now().Add(robotsTTL)
+	return p
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+# Comments and blank lines are ignored
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: job-hunter
+Disallow: /jobs/
+Allow: /jobs/search
+Disallow: /*.pdf$
+Crawl-delay: 0.2
+`
+
+func TestParseRobots(t *testing.T) {
+	p := parseRobots(strings.NewReader(testRobots), "job-hunter/1.0")
+	if p.crawlDelay != 200*time.Millisecond {
+		t.Errorf("Expected crawl delay 200ms, got %v", p.crawlDelay)
+	}
+
+	tests := map[string]bool{
+		"/":                    true,
+		"/about":               true,
+		"/jobs/view/123":       false,
+		"/jobs/search?q=go":    true,
+		"/files/resume.pdf":    false,
+		"/files/resume.pdf?x=": true,
+	}
+	for path, want := range tests {
+		if got := p.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	// Any other agent falls back to the "*" group.
+	if parseRobots(strings.NewReader(testRobots), "otherbot").allowed("/about") {
+		t.Error("Expected the wildcard group to disallow everything")
+	}
+}
+
+func TestRobotsTransport(t *testing.T) {
+	var robotsFetches, pageFetches int
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		if r.URL.Path == "/robots.txt" {
+			robotsFetches++
+			w.Write([]byte(testRobots))
+			return
+		}
+		pageFetches++
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	config := DefaultHTTPConfig()
+	config.RespectRobots = true
+	config.RobotsContact = "https://example.com/bot"
+	factory, err := NewClientFactory(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	client := factory.Client("Mock")
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := getPage(context.Background(), client, "Mock", server.URL+"/jobs/search?q=go", nil); err != nil {
+			t.Fatalf("Expected allowed page to load, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the crawl delay to space out requests, took %v", elapsed)
+	}
+
+	_, err = getPage(context.Background(), client, "Mock", server.URL+"/jobs/view/123", nil)
+	var robotsErr *RobotsBlockedError
+	if !errors.As(err, &robotsErr) {
+		t.Fatalf("Expected robots error, got %v", err)
+	}
+	if robotsFetches != 1 || pageFetches != 2 {
+		t.Errorf("Expected 1 robots.txt and 2 page fetches, got %d and %d", robotsFetches, pageFetches)
+	}
+	// The rules followed are those of the agent every request names,
+	// pages included, rather than a rotating browser User-Agent.
+	for _, ua := range userAgents {
+		if ua != "job-hunter/1.0 (+https://example.com/bot)" {
+			t.Errorf("Expected every request to identify as job-hunter, got %q", ua)
+		}
+	}
+
+	status := SourceStatus{}
+	classifyError(&status, err)
+	if status.ErrorClass != ErrorClassRobots || !status.Blocked {
+		t.Errorf("Expected a blocked-by-robots status, got %+v", status)
+	}
+}
+
+func TestRobotsBlockedLaterPage(t *testing.T) {
+	page := fixturePage(t, "linkedin")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			// The first page is allowed, the ones after it aren't.
+			io.WriteString(w, "User-agent: job-hunter\nDisallow: /*&start=\nAllow: /*&start=0$\n")
+			return
+		}
+		io.WriteString(w, page)
+	}))
+	defer server.Close()
+
+	oldURL := baseLinkedInURL
+	baseLinkedInURL = server.URL + "/jobs"
+	defer func() { baseLinkedInURL = oldURL }()
+
+	factory, err := NewClientFactory(HTTPConfig{RespectRobots: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	source := NewLinkedInCrawler(SourceOptions{
+		Client: factory.Client("LinkedIn"),
+		Config: SourceConfig{Pagination: Pagination{MaxPages: 3}},
+	})
+	result, err := NewJobCrawlerWithSources(Config{}, source).SearchJobs(context.Background(), JobSearchParams{Title: "golang"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s := result.Sources[0]; s.ErrorClass != ErrorClassRobots || !s.Blocked {
+		t.Errorf("Expected the source to be reported blocked by robots.txt, got %+v", s)
+	}
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags are the elements kept by sanitizeHTML. Everything else is
+// unwrapped, keeping its text, except droppedTags.
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.Strong: true, atom.B: true, atom.Em: true, atom.I: true, atom.U: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Blockquote: true, atom.A: true,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Noscript: true,
+	atom.Object: true, atom.Embed: true, atom.Form: true, atom.Button: true,
+	atom.Svg: true, atom.Template: true,
+}
+
+var bodyContext = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+
+// sanitizeHTML reduces a job description to a small set of formatting
+// tags that are safe to show: no scripts, styles, event handlers or
+// attributes other than http(s) link targets.
+func sanitizeHTML(s string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(s), bodyContext)
+	if err != nil {
+		return ""
+	}
+	// Some sites entity-encode the HTML, in which case the parse yields a
+	// single text node that is itself markup.
+	if len(nodes) == 1 && nodes[0].Type == html.TextNode && strings.Contains(nodes[0].Data, "<") {
+		if nodes, err = html.ParseFragment(strings.NewReader(nodes[0].Data), bodyContext); err != nil {
+			return ""
+		}
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		writeSafeHTML(&b, n)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func writeSafeHTML(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+		if droppedTags[n.DataAtom] {
+			return
+		}
+		if allowedTags[n.DataAtom] {
+			b.WriteString("<" + n.Data)
+			if n.DataAtom == atom.A {
+				if href := safeHref(n); href != "" {
+					b.WriteString(` href="` + html.EscapeString(href) + `" rel="nofollow noopener"`)
+				}
+			}
+			b.WriteString(">")
+			if n.DataAtom == atom.Br {
+				return
+			}
+			defer b.WriteString("</" + n.Data + ">")
+		}
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeSafeHTML(b, c)
+	}
+}
+
+// safeHref returns the link's target if it is an absolute http(s) URL.
+func safeHref(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key != "href" {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(a.Val))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ""
+		}
+		return u.String()
+	}
+	return ""
+}
@@ -0,0 +1,335 @@
+package crawler
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+	"job-hunter/internal/logger"
+	"job-hunter/internal/models"
+)
+
+// builtinSelectors are the selector specs shipped with the binary, one file
+// per source.
+//
+//go:embed selectors/*.yaml
+var builtinSelectors embed.FS
+
+// SelectorSpec declares how to pull job cards out of a results page. Specs
+// live in YAML or JSON files so a markup change on a site can be fixed by
+// shipping a new file instead of a new binary.
+type SelectorSpec struct {
+	// Source is the name of the source the spec is for.
+	Source string `yaml:"source" json:"source"`
+	// BaseURL resolves relative links in fields marked absolute.
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	// Card selects one element per job.
+	Card string `yaml:"card" json:"card"`
+	// Fields maps job fields (title, company, location, url, posted,
+	// salary, description) to how they are read from within a card.
+	Fields map[string]FieldSpec `yaml:"fields" json:"fields"`
+	// IDPattern is a regex whose first capture group reads the site's own
+	// ID for a job from the job's URL. Jobs it doesn't match get a hashed ID.
+	IDPattern string `yaml:"id_pattern,omitempty" json:"id_pattern,omitempty"`
+	// Required lists the fields a card must yield to count as a job.
+	// Defaults to title and company.
+	Required []string `yaml:"required,omitempty" json:"required,omitempty"`
+	// Detail maps detail fields (description, posted, salary,
+	// employment_type) to how they are read from a job's own page. It is
+	// used by the Enricher when the page has no JSON-LD.
+	Detail map[string]FieldSpec `yaml:"detail,omitempty" json:"detail,omitempty"`
+
+	base      *url.URL
+	idPattern *regexp.Regexp
+}
+
+// FieldSpec declares how one field is read from a card.
+type FieldSpec struct {
+	// Selector picks the element within the card; the first match is used.
+	// Empty means the card itself.
+	Selector string `yaml:"selector" json:"selector"`
+	// Attr reads an attribute instead of the element's text.
+	Attr string `yaml:"attr,omitempty" json:"attr,omitempty"`
+	// Exclude drops matching descendants before the text is read, e.g.
+	// screen-reader-only labels.
+	Exclude string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	// Strip lists literal strings removed from the value.
+	Strip []string `yaml:"strip,omitempty" json:"strip,omitempty"`
+	// Regex keeps only the part of the value it matches: the first capture
+	// group if it has one, the whole match otherwise.
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// Absolute resolves the value as a URL against the spec's BaseURL.
+	Absolute bool `yaml:"absolute,omitempty" json:"absolute,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// jobFields are the field names a spec may use.
+var jobFields = map[string]func(*models.Job, string){
+	"title":       func(j *models.Job, v string) { j.Title = v },
+	"company":     func(j *models.Job, v string) { j.Company = v },
+	"location":    func(j *models.Job, v string) { j.Location = v },
+	"url":         func(j *models.Job, v string) { j.URL = v },
+	"posted":      func(j *models.Job, v string) { j.PostedDate = parsePostedDate(v) },
+	"salary":      func(j *models.Job, v string) { j.Salary = v },
+	"description": func(j *models.Job, v string) { j.Description = v },
+}
+
+// detailFields are the field names a spec's detail section may use.
+var detailFields = map[string]bool{
+	"description":     true,
+	"posted":          true,
+	"salary":          true,
+	"employment_type": true,
+}
+
+// ParseSelectorSpec reads a spec from YAML or JSON and checks that its
+// selectors and regexes compile.
+func ParseSelectorSpec(data []byte) (*SelectorSpec, error) {
+	var spec SelectorSpec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	if err := spec.compile(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (s *SelectorSpec) compile() error {
+	if s.Source == "" {
+		return fmt.Errorf("selector spec has no source")
+	}
+	if _, err := cascadia.ParseGroup(s.Card); err != nil {
+		return fmt.Errorf("%s: card selector %q: %w", s.Source, s.Card, err)
+	}
+	if s.BaseURL != "" {
+		base, err := url.Parse(s.BaseURL)
+		if err != nil {
+			return fmt.Errorf("%s: base_url: %w", s.Source, err)
+		}
+		s.base = base
+	}
+	if s.IDPattern != "" {
+		re, err := regexp.Compile(s.IDPattern)
+		if err != nil {
+			return fmt.Errorf("%s: id_pattern: %w", s.Source, err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("%s: id_pattern has no capture group", s.Source)
+		}
+		s.idPattern = re
+	}
+	if len(s.Required) == 0 {
+		s.Required = []string{"title", "company"}
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Fields[name]; !ok {
+			return fmt.Errorf("%s: required field %q has no selector", s.Source, name)
+		}
+	}
+
+	for name, f := range s.Fields {
+		if _, ok := jobFields[name]; !ok {
+			return fmt.Errorf("%s: unknown field %q", s.Source, name)
+		}
+		if err := s.compileField(name, &f); err != nil {
+			return err
+		}
+		s.Fields[name] = f
+	}
+	for name, f := range s.Detail {
+		if !detailFields[name] {
+			return fmt.Errorf("%s: unknown detail field %q", s.Source, name)
+		}
+		if err := s.compileField("detail "+name, &f); err != nil {
+			return err
+		}
+		s.Detail[name] = f
+	}
+	return nil
+}
+
+func (s *SelectorSpec) compileField(name string, f *FieldSpec) error {
+	for _, sel := range []string{f.Selector, f.Exclude} {
+		if sel == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(sel); err != nil {
+			return fmt.Errorf("%s: field %s: selector %q: %w", s.Source, name, sel, err)
+		}
+	}
+	if f.Regex != "" {
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return fmt.Errorf("%s: field %s: %w", s.Source, name, err)
+		}
+		f.re = re
+	}
+	if f.Absolute && s.base == nil {
+		return fmt.Errorf("%s: field %s is absolute but the spec has no base_url", s.Source, name)
+	}
+	return nil
+}
+
+// Extract runs the spec against a results page and returns one job per card
+// that yields all required fields.
+func (s *SelectorSpec) Extract(body []byte) ([]models.Job, error) {
+	log := logger.Get().With().Str("source", s.Source).Logger()
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse HTML")
+		return nil, &ParseError{Err: err}
+	}
+
+	var jobs []models.Job
+	doc.Find(s.Card).Each(func(_ int, card *goquery.Selection) {
+		job := models.Job{Source: s.Source}
+		values := make(map[string]string, len(s.Fields))
+		for name, f := range s.Fields {
+			v := s.value(card, f)
+			values[name] = v
+			if v != "" {
+				jobFields[name](&job, v)
+			}
+		}
+
+		for _, name := range s.Required {
+			if values[name] == "" {
+				log.Debug().Str("title", job.Title).Str("company", job.Company).Str("missing", name).Msg("Skipping incomplete job")
+				return
+			}
+		}
+
+		job.ID = JobID(s.Source, s.nativeID(job.URL), job)
+		log.Debug().Str("title", job.Title).Str("company", job.Company).Str("location", job.Location).Msg("Found job")
+		jobs = append(jobs, job)
+	})
+	return jobs, nil
+}
+
+// value reads one field from a card.
+func (s *SelectorSpec) value(card *goquery.Selection, f FieldSpec) string {
+	sel := card
+	if f.Selector != "" {
+		sel = card.Find(f.Selector).First()
+	}
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	var v string
+	if f.Attr != "" {
+		v, _ = sel.Attr(f.Attr)
+	} else {
+		if f.Exclude != "" {
+			sel = sel.Clone()
+			sel.Find(f.Exclude).Remove()
+		}
+		v = blockText(sel)
+	}
+	for _, strip := range f.Strip {
+		v = strings.ReplaceAll(v, strip, "")
+	}
+	v = cleanText(v)
+
+	if f.re != nil {
+		m := f.re.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+			v = ""
+		case len(m) > 1:
+			v = m[1]
+		default:
+			v = m[0]
+		}
+	}
+	if f.Absolute && v != "" {
+		ref, err := url.Parse(v)
+		if err != nil {
+			return ""
+		}
+		v = s.base.ResolveReference(ref).String()
+	}
+	return v
+}
+
+// LoadSelectors returns the built-in selector specs keyed by lowercased
+// source name, with any spec files (*.yaml, *.yml, *.json) in dir
+// replacing the built-in spec for their source. An empty dir loads only the
+// built-in specs.
+func LoadSelectors(dir string) (map[string]*SelectorSpec, error) {
+	specs, err := loadSelectorFS(builtinSelectors, "selectors")
+	if err != nil {
+		return nil, fmt.Errorf("built-in selectors: %w", err)
+	}
+	if dir == "" {
+		return specs, nil
+	}
+	overrides, err := loadSelectorFS(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, fmt.Errorf("loading selectors from %s: %w", dir, err)
+	}
+	log := logger.Get()
+	for name, spec := range overrides {
+		log.Info().Str("source", spec.Source).Str("dir", dir).Msg("Using selector override")
+		specs[name] = spec
+	}
+	return specs, nil
+}
+
+func loadSelectorFS(fsys fs.FS, dir string) (map[string]*SelectorSpec, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	specs := make(map[string]*SelectorSpec)
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		spec, err := ParseSelectorSpec(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		specs[strings.ToLower(spec.Source)] = spec
+	}
+	return specs, nil
+}
+
+var (
+	builtinSpecsOnce sync.Once
+	builtinSpecs     map[string]*SelectorSpec
+)
+
+// builtinSelector returns the built-in spec for source.
+func builtinSelector(source string) *SelectorSpec {
+	builtinSpecsOnce.Do(func() {
+		var err error
+		builtinSpecs, err = LoadSelectors("")
+		if err != nil {
+			panic(err) // the embedded specs are checked by the tests
+		}
+	})
+	return builtinSpecs[strings.ToLower(source)]
+}
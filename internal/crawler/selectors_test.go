@@ -0,0 +1,144 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinSelectors(t *testing.T) {
+	specs, err := LoadSelectors("")
+	if err != nil {
+		t.Fatalf("Expected built-in selectors to load, got %v", err)
+	}
+	for _, name := range []string{"LinkedIn", "Indeed", "Monster", "Glassdoor"} {
+		if specs[strings.ToLower(name)] == nil {
+			t.Errorf("Expected a built-in selector spec for %s", name)
+		}
+	}
+}
+
+func TestSelectorSpecExtract(t *testing.T) {
+	spec, err := ParseSelectorSpec([]byte(`
+source: Example
+base_url: https://jobs.example.com/search
+card: li.job
+id_pattern: '/jobs/(\d+)'
+fields:
+  title:
+    selector: h2
+  company:
+    selector: .company
+    strip: ["Company:"]
+  url:
+    selector: a
+    attr: href
+    absolute: true
+  salary:
+    selector: .pay
+    regex: '(\$[\d,]+)'
+  posted:
+    selector: .age
+    exclude: .sr-only
+`))
+	if err != nil {
+		t.Fatalf("Expected spec to parse, got %v", err)
+	}
+
+	now := time.Date(2025, 4, 14, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	jobs, err := spec.Extract([]byte(`
+<ul>
+  <li class="job">
+    <h2>
+      Go   Engineer
+    </h2>
+    <span class="company">Company: Acme</span>
+    <a href="/jobs/1?ref=list">Apply</a>
+    <span class="pay">Up to $150,000 a year</span>
+    <span class="age"><span class="sr-only">Posted</span>2 days ago</span>
+  </li>
+  <li class="job">
+    <h2>No company</h2>
+  </li>
+</ul>`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Expected the incomplete card to be skipped, got %d jobs", len(jobs))
+	}
+
+	job := jobs[0]
+	if job.Title != "Go Engineer" {
+		t.Errorf("Expected collapsed title, got %q", job.Title)
+	}
+	if job.Company != "Acme" {
+		t.Errorf("Expected stripped company, got %q", job.Company)
+	}
+	if job.URL != "https://jobs.example.com/jobs/1?ref=list" {
+		t.Errorf("Expected absolute URL, got %q", job.URL)
+	}
+	if job.Salary != "$150,000" {
+		t.Errorf("Expected salary from regex, got %q", job.Salary)
+	}
+	if want := now.AddDate(0, 0, -2); !job.PostedDate.Equal(want) {
+		t.Errorf("Expected posted date %v, got %v", want, job.PostedDate)
+	}
+	if job.Source != "Example" || job.ID != "example:1" {
+		t.Errorf("Expected source and ID to be set, got %q and %q", job.Source, job.ID)
+	}
+}
+
+func TestParseSelectorSpecErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad card":         "source: X\ncard: 'li[['\nfields: {title: {selector: h2}, company: {selector: p}}",
+		"unknown field":    "source: X\ncard: li\nfields: {title: {selector: h2}, company: {selector: p}, colour: {selector: b}}",
+		"missing required": "source: X\ncard: li\nfields: {title: {selector: h2}}",
+		"bad regex":        "source: X\ncard: li\nfields: {title: {selector: h2, regex: '('}, company: {selector: p}}",
+		"no base url":      "source: X\ncard: li\nfields: {title: {selector: h2}, company: {selector: p}, url: {selector: a, attr: href, absolute: true}}",
+		"id pattern":       "source: X\ncard: li\nid_pattern: 'jk=\\w+'\nfields: {title: {selector: h2}, company: {selector: p}}",
+		"unknown key":      "source: X\ncard: li\ncards: li\nfields: {title: {selector: h2}, company: {selector: p}}",
+	}
+	for name, spec := range tests {
+		if _, err := ParseSelectorSpec([]byte(spec)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadSelectorsOverride(t *testing.T) {
+	dir := t.TempDir()
+	override := `{
+  "source": "LinkedIn",
+  "card": "div.new-card",
+  "fields": {
+    "title": {"selector": ".new-title"},
+    "company": {"selector": ".new-company"}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "linkedin.json"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, err := LoadSelectors(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if specs["linkedin"].Card != "div.new-card" {
+		t.Errorf("Expected the override to replace the built-in LinkedIn spec, got card %q", specs["linkedin"].Card)
+	}
+	if specs["indeed"] == nil {
+		t.Error("Expected sources without an override to keep their built-in spec")
+	}
+
+	source := NewLinkedInCrawler(SourceOptions{Selectors: specs})
+	jobs, err := source.selectors.Extract([]byte(`<div class="new-card"><p class="new-title">SRE</p><p class="new-company">Acme</p></div>`))
+	if err != nil || len(jobs) != 1 || jobs[0].Title != "SRE" {
+		t.Errorf("Expected the crawler to use the override, got %v, %v", jobs, err)
+	}
+}
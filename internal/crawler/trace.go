@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+
+	"job-hunter/internal/models"
+)
+
+// SearchTrace holds hooks that SearchJobs calls as each source progresses,
+// so callers can report on a search before it completes. Any hook may be
+// nil. Hooks are called from the crawler's workers, concurrently, and
+// should return quickly.
+type SearchTrace struct {
+	// SourceStarted is called when a source begins crawling, after waiting
+	// out its politeness delay.
+	SourceStarted func(source string)
+	// SourceDone is called when a source finishes, with its status and the
+	// jobs it found. The jobs are normalized but not yet merged with other
+	// sources' listings or enriched.
+	SourceDone func(status SourceStatus, jobs []models.Job)
+}
+
+type searchTraceKey struct{}
+
+// WithSearchTrace returns a context that makes searches run with it call
+// the hooks of trace.
+func WithSearchTrace(ctx context.Context, trace *SearchTrace) context.Context {
+	return context.WithValue(ctx, searchTraceKey{}, trace)
+}
+
+// ContextSearchTrace returns the trace of ctx, or nil if it has none.
+func ContextSearchTrace(ctx context.Context) *SearchTrace {
+	trace, _ := ctx.Value(searchTraceKey{}).(*SearchTrace)
+	return trace
+}
+
+func (t *SearchTrace) sourceStarted(source string) {
+	if t != nil && t.SourceStarted != nil {
+		t.SourceStarted(source)
+	}
+}
+
+func (t *SearchTrace) sourceDone(status SourceStatus, jobs []models.Job) {
+	if t != nil && t.SourceDone != nil {
+		t.SourceDone(status, jobs)
+	}
+}
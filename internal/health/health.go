@@ -0,0 +1,323 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/logger"
+)
+
+// AlarmKind tells what looks wrong with a source.
+type AlarmKind string
+
+const (
+	// AlarmJobCountDrop means a source returned far fewer jobs than usual
+	// for the same search.
+	AlarmJobCountDrop AlarmKind = "job_count_drop"
+	// AlarmFieldEmpty means a field that used to be filled on most jobs is
+	// now mostly empty, i.e. its selector probably stopped matching.
+	AlarmFieldEmpty AlarmKind = "field_empty"
+)
+
+// Alarm is a sign that a source's selectors have drifted from the site's
+// markup even though the crawl itself succeeded.
+type Alarm struct {
+	Source   string    `json:"source"`
+	Kind     AlarmKind `json:"kind"`
+	Field    string    `json:"field,omitempty"`
+	Baseline float64   `json:"baseline"`
+	Current  float64   `json:"current"`
+	Message  string    `json:"message"`
+}
+
+// Sample is what one source returned in one run.
+type Sample struct {
+	Source    string             `json:"source"`
+	JobCount  int                `json:"job_count"`
+	FillRates map[string]float64 `json:"fill_rates,omitempty"`
+}
+
+// Run is one recorded search.
+type Run struct {
+	At      time.Time `json:"at"`
+	Query   string    `json:"query"`
+	Samples []Sample  `json:"samples"`
+	Alarms  []Alarm   `json:"alarms,omitempty"`
+}
+
+// Options tunes how sensitive the monitor is.
+type Options struct {
+	// Window is how many past samples of a source make up its baseline.
+	Window int
+	// MinSamples is how many past samples a source needs before it is
+	// compared against its baseline at all.
+	MinSamples int
+	// MaxRuns caps the history kept on disk.
+	MaxRuns int
+	// DropRatio raises AlarmJobCountDrop when the job count falls below
+	// this fraction of the baseline.
+	DropRatio float64
+	// MinBaselineJobs keeps searches that normally return only a handful
+	// of jobs from raising count alarms.
+	MinBaselineJobs float64
+	// FillDrop raises AlarmFieldEmpty when a field's fill rate falls by at
+	// least this much (in absolute terms) below its baseline.
+	FillDrop float64
+}
+
+// DefaultOptions returns the options used by the CLI and the API server.
+func DefaultOptions() Options {
+	return Options{
+		Window:          7,
+		MinSamples:      3,
+		MaxRuns:         200,
+		DropRatio:       0.5,
+		MinBaselineJobs: 5,
+		FillDrop:        0.5,
+	}
+}
+
+// Monitor keeps a rolling history of per-source results in a JSON file and
+// raises alarms when a run falls well short of the history.
+type Monitor struct {
+	path string
+	opts Options
+	mu   sync.Mutex
+}
+
+// NewMonitor returns a monitor that keeps its history in path.
+func NewMonitor(path string, opts Options) *Monitor {
+	return &Monitor{path: path, opts: opts}
+}
+
+// QueryKey identifies a search in the history. Job counts are only
+// compared between runs of the same search, filters included.
+func QueryKey(params crawler.JobSearchParams) string {
+	key := strings.ToLower(strings.TrimSpace(params.Title)) + "|" + strings.ToLower(strings.TrimSpace(params.Location))
+	if filters := params.FilterKey(); filters != "" {
+		key += "|" + filters
+	}
+	return key
+}
+
+// Record compares result with the history, appends it, and returns the
+// alarms it raised. Sources that failed outright are left out: their
+// failure is already reported, and they would drag the baseline down.
+func (m *Monitor) Record(params crawler.JobSearchParams, result *crawler.SearchResult) ([]Alarm, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	run := Run{At: time.Now().UTC(), Query: QueryKey(params)}
+	for _, s := range result.Sources {
+		if !s.OK() {
+			continue
+		}
+		sample := Sample{Source: s.Name, JobCount: s.JobCount, FillRates: s.FillRates}
+		run.Samples = append(run.Samples, sample)
+		run.Alarms = append(run.Alarms, m.check(runs, run.Query, sample)...)
+	}
+
+	log := logger.Get()
+	for _, a := range run.Alarms {
+		log.Warn().Str("source", a.Source).Str("kind", string(a.Kind)).Str("field", a.Field).
+			Float64("baseline", a.Baseline).Float64("current", a.Current).Msg(a.Message)
+	}
+
+	runs = append(runs, run)
+	if m.opts.MaxRuns > 0 && len(runs) > m.opts.MaxRuns {
+		runs = runs[len(runs)-m.opts.MaxRuns:]
+	}
+	if err := m.save(runs); err != nil {
+		return run.Alarms, err
+	}
+	return run.Alarms, nil
+}
+
+// baseline is what a source usually returns.
+type baseline struct {
+	samples   int
+	jobCount  float64 // median over runs of the same query
+	fillRates map[string]float64
+	fillRuns  int // samples with jobs, which the fill rates come from
+}
+
+// baselineFor builds the source's baseline from the most recent samples in
+// runs. Fill rates don't depend on the query, so they use every run.
+func (m *Monitor) baselineFor(runs []Run, query, source string) baseline {
+	var counts []float64
+	fills := make(map[string][]float64)
+	fillRuns := 0
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, s := range runs[i].Samples {
+			if s.Source != source {
+				continue
+			}
+			if runs[i].Query == query && len(counts) < m.opts.Window {
+				counts = append(counts, float64(s.JobCount))
+			}
+			if s.JobCount > 0 && fillRuns < m.opts.Window {
+				fillRuns++
+				for field, rate := range s.FillRates {
+					fills[field] = append(fills[field], rate)
+				}
+			}
+		}
+	}
+
+	b := baseline{samples: len(counts), jobCount: median(counts), fillRuns: fillRuns}
+	if fillRuns > 0 {
+		b.fillRates = make(map[string]float64, len(fills))
+		for field, rates := range fills {
+			b.fillRates[field] = median(rates)
+		}
+	}
+	return b
+}
+
+// check compares one sample against the source's baseline.
+func (m *Monitor) check(runs []Run, query string, sample Sample) []Alarm {
+	b := m.baselineFor(runs, query, sample.Source)
+	var alarms []Alarm
+
+	if b.samples >= m.opts.MinSamples && b.jobCount >= m.opts.MinBaselineJobs &&
+		float64(sample.JobCount) < b.jobCount*m.opts.DropRatio {
+		alarms = append(alarms, Alarm{
+			Source:   sample.Source,
+			Kind:     AlarmJobCountDrop,
+			Baseline: b.jobCount,
+			Current:  float64(sample.JobCount),
+			Message: fmt.Sprintf("%s returned %d jobs, usually around %.0f",
+				sample.Source, sample.JobCount, b.jobCount),
+		})
+	}
+
+	if sample.JobCount > 0 && b.fillRuns >= m.opts.MinSamples {
+		for _, field := range crawler.TrackedFields {
+			usual, current := b.fillRates[field], sample.FillRates[field]
+			if usual-current >= m.opts.FillDrop {
+				alarms = append(alarms, Alarm{
+					Source:   sample.Source,
+					Kind:     AlarmFieldEmpty,
+					Field:    field,
+					Baseline: usual,
+					Current:  current,
+					Message: fmt.Sprintf("%s filled %s on %.0f%% of jobs, usually %.0f%%",
+						sample.Source, field, current*100, usual*100),
+				})
+			}
+		}
+	}
+	return alarms
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// SourceHealth summarises the latest recorded run of one source.
+type SourceHealth struct {
+	Source            string             `json:"source"`
+	Healthy           bool               `json:"healthy"`
+	LastRun           time.Time          `json:"last_run"`
+	JobCount          int                `json:"job_count"`
+	BaselineJobCount  float64            `json:"baseline_job_count"`
+	FillRates         map[string]float64 `json:"fill_rates,omitempty"`
+	BaselineFillRates map[string]float64 `json:"baseline_fill_rates,omitempty"`
+	Alarms            []Alarm            `json:"alarms"`
+}
+
+// Sources returns the health of every source in the history, by name.
+func (m *Monitor) Sources() ([]SourceHealth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var sources []SourceHealth
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		for _, s := range run.Samples {
+			if seen[s.Source] {
+				continue
+			}
+			seen[s.Source] = true
+
+			b := m.baselineFor(runs[:i], run.Query, s.Source)
+			h := SourceHealth{
+				Source:            s.Source,
+				LastRun:           run.At,
+				JobCount:          s.JobCount,
+				BaselineJobCount:  b.jobCount,
+				FillRates:         s.FillRates,
+				BaselineFillRates: b.fillRates,
+				Alarms:            []Alarm{},
+			}
+			for _, a := range run.Alarms {
+				if a.Source == s.Source {
+					h.Alarms = append(h.Alarms, a)
+				}
+			}
+			h.Healthy = len(h.Alarms) == 0
+			sources = append(sources, h)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Source < sources[j].Source })
+	return sources, nil
+}
+
+func (m *Monitor) load() ([]Run, error) {
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading health history: %w", err)
+	}
+	var runs []Run
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("parsing health history %s: %w", m.path, err)
+	}
+	return runs, nil
+}
+
+// save writes through a temp file so a crash never leaves a truncated
+// history behind.
+func (m *Monitor) save(runs []Run) error {
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing health history: %w", err)
+	}
+	return os.Rename(tmp, m.path)
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"path/filepath"
+	"testing"
+
+	"job-hunter/internal/crawler"
+)
+
+func status(name string, jobs int, rates map[string]float64) crawler.SourceStatus {
+	return crawler.SourceStatus{Name: name, JobCount: jobs, FillRates: rates}
+}
+
+func fullRates() map[string]float64 {
+	return map[string]float64{"title": 1, "company": 1, "location": 1, "url": 1, "posted_date": 0.9}
+}
+
+func TestMonitorAlarms(t *testing.T) {
+	m := NewMonitor(filepath.Join(t.TempDir(), "health.json"), DefaultOptions())
+	params := crawler.JobSearchParams{Title: "Go Developer", Location: "Remote"}
+
+	// Build up a baseline; nothing to compare against yet.
+	for _, n := range []int{40, 50, 45} {
+		alarms, err := m.Record(params, &crawler.SearchResult{Sources: []crawler.SourceStatus{
+			status("LinkedIn", n, fullRates()),
+			status("Indeed", 20, fullRates()),
+		}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(alarms) != 0 {
+			t.Fatalf("Expected no alarms while building the baseline, got %+v", alarms)
+		}
+	}
+
+	// LinkedIn comes back nearly empty, Indeed loses its company selector,
+	// and a failed source is ignored.
+	broken := fullRates()
+	broken["company"] = 0
+	alarms, err := m.Record(params, &crawler.SearchResult{Sources: []crawler.SourceStatus{
+		status("LinkedIn", 0, nil),
+		status("Indeed", 19, broken),
+		{Name: "Monster", ErrorClass: crawler.ErrorClassTimeout},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(alarms) != 2 {
+		t.Fatalf("Expected 2 alarms, got %+v", alarms)
+	}
+	if a := alarms[0]; a.Source != "LinkedIn" || a.Kind != AlarmJobCountDrop || a.Baseline != 45 || a.Current != 0 {
+		t.Errorf("Expected a LinkedIn job count drop from 45, got %+v", a)
+	}
+	if a := alarms[1]; a.Source != "Indeed" || a.Kind != AlarmFieldEmpty || a.Field != "company" {
+		t.Errorf("Expected an Indeed company field alarm, got %+v", a)
+	}
+
+	sources, err := m.Sources()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources in the health summary, got %+v", sources)
+	}
+	for _, s := range sources {
+		if s.Healthy || len(s.Alarms) != 1 {
+			t.Errorf("Expected %s to be unhealthy with 1 alarm, got %+v", s.Source, s)
+		}
+	}
+	if sources[1].Source != "LinkedIn" || sources[1].BaselineJobCount != 45 {
+		t.Errorf("Expected LinkedIn's baseline of 45, got %+v", sources[1])
+	}
+}
+
+func TestMonitorComparesSameQueryOnly(t *testing.T) {
+	m := NewMonitor(filepath.Join(t.TempDir(), "health.json"), DefaultOptions())
+	broad := crawler.JobSearchParams{Title: "engineer"}
+	narrow := crawler.JobSearchParams{Title: "cobol mainframe engineer", Location: "Reykjavik"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.Record(broad, &crawler.SearchResult{Sources: []crawler.SourceStatus{status("LinkedIn", 100, fullRates())}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alarms, err := m.Record(narrow, &crawler.SearchResult{Sources: []crawler.SourceStatus{status("LinkedIn", 2, fullRates())}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alarms) != 0 {
+		t.Errorf("Expected a different search not to be held to the broad search's job count, got %+v", alarms)
+	}
+}
@@ -3,13 +3,28 @@ package models
 import "time"
 
 type Job struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Company     string    `json:"company"`
-	Location    string    `json:"location"`
-	Description string    `json:"description"`
-	URL         string    `json:"url"`
-	Source      string    `json:"source"`
-	Salary      string    `json:"salary,omitempty"`
-	PostedDate  time.Time `json:"posted_date"`
+	ID              string      `json:"id"`
+	Title           string      `json:"title"`
+	Company         string      `json:"company"`
+	Location        string      `json:"location"`
+	Description     string      `json:"description"`
+	DescriptionHTML string      `json:"description_html,omitempty"` // Sanitized HTML of the full description, when the detail page was read
+	URL             string      `json:"url"`
+	Source          string      `json:"source"`
+	Salary          string      `json:"salary,omitempty"`
+	SalaryMin       float64     `json:"salary_min,omitempty"`      // Lower end of the salary range, when stated in machine-readable form
+	SalaryMax       float64     `json:"salary_max,omitempty"`      // Upper end of the salary range
+	SalaryCurrency  string      `json:"salary_currency,omitempty"` // ISO 4217 code, e.g. "USD"
+	SalaryPeriod    string      `json:"salary_period,omitempty"`   // What the range is paid per, e.g. "year" or "hour"
+	EmploymentType  string      `json:"employment_type,omitempty"` // e.g. "full_time", "contractor"
+	PostedDate      time.Time   `json:"posted_date,omitzero"`
+	EnrichedAt      time.Time   `json:"enriched_at,omitzero"` // When the job's detail page was read
+	Sources         []SourceRef `json:"sources,omitempty"`    // Every listing of this job when it was found on several sources, this one first
+}
+
+// SourceRef points at one listing of a job that several sources carry.
+type SourceRef struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	URL    string `json:"url"`
 }
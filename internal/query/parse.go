@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField // A field prefix; text is the field name
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokPhrase:
+		return `"` + t.text + `"`
+	case tokField:
+		return t.text + ":"
+	}
+	return t.text
+}
+
+// lex splits a query into tokens.
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated phrase at offset %d", i)
+			}
+			tokens = append(tokens, token{tokPhrase, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c == '-' && i+1 < len(s) && !strings.ContainsRune(" \t\n\r)", rune(s[i+1])):
+			tokens = append(tokens, token{tokNot, "-", i})
+			i++
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n\r()\"", rune(s[end])) {
+				end++
+			}
+			word := s[i:end]
+			if name, _, ok := strings.Cut(word, ":"); ok {
+				field := strings.ToLower(name)
+				if !slices.Contains(Fields, field) {
+					return nil, fmt.Errorf("unknown field %q at offset %d (one of: %s)", name, i, strings.Join(Fields, ", "))
+				}
+				// What follows the colon is lexed on its own, so it can
+				// be a phrase or a group.
+				tokens = append(tokens, token{tokField, field, i})
+				i += len(name) + 1
+				continue
+			}
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind, word, i})
+			i = end
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(s)}), nil
+}
+
+// parser is a recursive descent parser over the tokens of a query. Its
+// methods take the field prefix that applies to the terms they parse, if
+// any.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// atTerm reports whether the next token starts a term, which makes it
+// part of an implicit AND.
+func (p *parser) atTerm() bool {
+	switch p.peek().kind {
+	case tokWord, tokPhrase, tokField, tokLParen, tokNot:
+		return true
+	}
+	return false
+}
+
+// parseOr parses terms joined by OR.
+func (p *parser) parseOr(field string) (node, error) {
+	var terms orNode
+	for {
+		x, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+		if p.peek().kind != tokOr {
+			break
+		}
+		p.next()
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+// parseAnd parses terms joined by AND or written next to each other.
+func (p *parser) parseAnd(field string) (node, error) {
+	var terms andNode
+	for {
+		x, err := p.parseNot(field)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+
+		if p.peek().kind == tokAnd {
+			p.next()
+		} else if !p.atTerm() {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *parser) parseNot(field string) (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		x, err := p.parseNot(field)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parseTerm(field)
+}
+
+// parseTerm parses a word, a phrase or a group, with its field prefix.
+func (p *parser) parseTerm(field string) (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokField:
+		return p.parseTerm(tok.text)
+	case tokLParen:
+		x, err := p.parseOr(field)
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) to close ( at offset %d, got %s", tok.pos, end)
+		}
+		return x, nil
+	case tokWord, tokPhrase:
+		words := splitWords(tok.text, true)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%s at offset %d has no words to match", tok, tok.pos)
+		}
+		return &term{field: field, words: words}, nil
+	}
+	return nil, fmt.Errorf("expected a term at offset %d, got %s", tok.pos, tok)
+}
@@ -0,0 +1,280 @@
+// Package query parses and evaluates boolean queries over jobs, such as
+//
+//	(golang OR rust) AND NOT (intern OR "staff augmentation") AND title:director
+//
+// Terms are words or quoted phrases, matched case-insensitively against
+// whole words of a job's title, company, location and description. A
+// field prefix (title:, company:, location:, description: or source:)
+// limits a term or a parenthesized group to one field. In words, * matches
+// any run of characters and ? a single one. Terms next to each other must
+// all match, as with AND; AND, OR and NOT must be written in capitals, and
+// -term is short for NOT term. NOT binds tightest, then AND, then OR.
+package query
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"job-hunter/internal/models"
+)
+
+// Fields a term can be limited to with a prefix such as "title:".
+const (
+	FieldTitle       = "title"
+	FieldCompany     = "company"
+	FieldLocation    = "location"
+	FieldDescription = "description"
+	FieldSource      = "source"
+)
+
+// Fields are the fields a query can name.
+var Fields = []string{FieldTitle, FieldCompany, FieldLocation, FieldDescription, FieldSource}
+
+// defaultFields are searched by terms without a field prefix.
+var defaultFields = []string{FieldTitle, FieldCompany, FieldLocation, FieldDescription}
+
+// Query is a parsed query. The zero value isn't usable; use Parse.
+type Query struct {
+	root node
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+	root, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", tok, tok.pos)
+	}
+	return &Query{root: root}, nil
+}
+
+// String returns the query in a canonical form: lowercased terms, explicit
+// operators, and parentheses around every OR.
+func (q *Query) String() string {
+	return q.root.String()
+}
+
+// Match reports whether job may match the query. Jobs without a
+// description, which most sources don't list, only fail when the fields
+// they have are enough to tell: "golang" alone keeps a job whose title
+// doesn't mention it, "title:golang" doesn't.
+func (q *Query) Match(job models.Job) bool {
+	return q.root.eval(&document{job: job}) != no
+}
+
+// Keywords returns the terms of the query simple enough for a site's own
+// search box: the words and phrases that every match contains, and the
+// ones no match contains. Queries with OR at the top have none.
+func (q *Query) Keywords() (include, exclude []string) {
+	conjuncts := []node{q.root}
+	if and, ok := q.root.(andNode); ok {
+		conjuncts = and
+	}
+	for _, n := range conjuncts {
+		switch n := n.(type) {
+		case *term:
+			if (n.field == "" || n.field == FieldTitle) && !n.wildcard() {
+				include = append(include, strings.Join(n.words, " "))
+			}
+		case notNode:
+			if t, ok := n.x.(*term); ok && t.field == "" && !t.wildcard() {
+				exclude = append(exclude, strings.Join(t.words, " "))
+			}
+		}
+	}
+	return include, exclude
+}
+
+// truth is the result of evaluating a query against a job whose
+// description may be unknown. It is ordered so that AND is min, OR is max
+// and NOT is 2-x.
+type truth int8
+
+const (
+	no truth = iota
+	unknown
+	yes
+)
+
+type node interface {
+	eval(d *document) truth
+	String() string
+}
+
+type andNode []node
+
+func (n andNode) eval(d *document) truth {
+	result := yes
+	for _, x := range n {
+		if result = min(result, x.eval(d)); result == no {
+			break
+		}
+	}
+	return result
+}
+
+func (n andNode) String() string {
+	parts := make([]string, len(n))
+	for i, x := range n {
+		parts[i] = x.String()
+	}
+	return strings.Join(parts, " AND ")
+}
+
+type orNode []node
+
+func (n orNode) eval(d *document) truth {
+	result := no
+	for _, x := range n {
+		if result = max(result, x.eval(d)); result == yes {
+			break
+		}
+	}
+	return result
+}
+
+func (n orNode) String() string {
+	parts := make([]string, len(n))
+	for i, x := range n {
+		parts[i] = x.String()
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+type notNode struct {
+	x node
+}
+
+func (n notNode) eval(d *document) truth {
+	return yes - n.x.eval(d)
+}
+
+func (n notNode) String() string {
+	if and, ok := n.x.(andNode); ok {
+		return "NOT (" + and.String() + ")"
+	}
+	return "NOT " + n.x.String()
+}
+
+// term is a word or phrase, split into lowercased words that may hold
+// wildcards. It matches when the words appear in a row in one of its
+// fields.
+type term struct {
+	field string // Empty for the default fields
+	words []string
+}
+
+func (t *term) wildcard() bool {
+	for _, w := range t.words {
+		if strings.ContainsAny(w, "*?") {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *term) eval(d *document) truth {
+	fields := defaultFields
+	if t.field != "" {
+		fields = []string{t.field}
+	}
+	result := no
+	for _, field := range fields {
+		words := d.words(field)
+		if len(words) == 0 && field == FieldDescription {
+			result = unknown
+			continue
+		}
+		if t.matches(words) {
+			return yes
+		}
+	}
+	return result
+}
+
+func (t *term) matches(words []string) bool {
+	for i := 0; i+len(t.words) <= len(words); i++ {
+		if t.matchesAt(words[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *term) matchesAt(words []string) bool {
+	for i, pattern := range t.words {
+		if ok, _ := path.Match(pattern, words[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *term) String() string {
+	s := strings.Join(t.words, " ")
+	if len(t.words) > 1 {
+		s = `"` + s + `"`
+	}
+	if t.field != "" {
+		s = t.field + ":" + s
+	}
+	return s
+}
+
+// document is a job being matched, with the words of its fields split out
+// as they are needed.
+type document struct {
+	job   models.Job
+	split map[string][]string
+}
+
+func (d *document) words(field string) []string {
+	if words, ok := d.split[field]; ok {
+		return words
+	}
+	var text string
+	switch field {
+	case FieldTitle:
+		text = d.job.Title
+	case FieldCompany:
+		text = d.job.Company
+	case FieldLocation:
+		text = d.job.Location
+	case FieldDescription:
+		text = d.job.Description
+	case FieldSource:
+		text = d.job.Source
+		for _, ref := range d.job.Sources {
+			text += " " + ref.Source
+		}
+	}
+	if d.split == nil {
+		d.split = make(map[string][]string)
+	}
+	d.split[field] = splitWords(text, false)
+	return d.split[field]
+}
+
+// splitWords lowercases s and splits it into words of letters, digits, +
+// and #, so "C++" and "C#" are words of their own. With wildcards, * and ?
+// are part of words too.
+func splitWords(s string, wildcards bool) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		if wildcards && (r == '*' || r == '?') {
+			return false
+		}
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+}
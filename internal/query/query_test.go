@@ -0,0 +1,102 @@
+package query
+
+import (
+	"slices"
+	"testing"
+
+	"job-hunter/internal/models"
+)
+
+func TestMatch(t *testing.T) {
+	director := models.Job{
+		Title:       "Director of Engineering",
+		Company:     "Acme",
+		Location:    "Austin, TX",
+		Description: "Lead our Golang and Rust teams. No staff augmentation.",
+		Source:      "LinkedIn",
+		Sources:     []models.SourceRef{{Source: "LinkedIn"}, {Source: "Indeed"}},
+	}
+	intern := models.Job{Title: "Go Intern", Company: "Node.js Shop", Location: "Remote", Source: "Indeed"}
+
+	for _, tt := range []struct {
+		query string
+		job   models.Job
+		want  bool
+	}{
+		{`(golang OR rust) AND NOT (intern OR "staff augmentation") AND title:director`, director, false},
+		{`(golang OR rust) AND NOT intern AND title:director`, director, true},
+		{`golang rust`, director, true},
+		{`golang -rust`, director, false},
+		{`"engineering director"`, director, false},
+		{`title:"director of engineering"`, director, true},
+		{`company:(acme OR hooli)`, director, true},
+		{`location:remote`, director, false},
+		{`source:indeed`, director, true},
+		{`title:engin*`, director, true},
+		{`title:eng`, director, false},
+		{`dir?ctor`, director, true},
+		{`golang OR python AND java`, director, true},
+		{`NOT NOT golang`, director, true},
+
+		// Without a description, terms it could hold are left open.
+		{`golang`, intern, true},
+		{`title:golang`, intern, false},
+		{`NOT kubernetes`, intern, true},
+		{`intern AND NOT remote`, intern, false},
+		{`company:"node js"`, intern, true},
+	} {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(tt.job); got != tt.want {
+			t.Errorf("%s: expected %v for %q, got %v", tt.query, tt.want, tt.job.Title, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{`(golang OR Rust) AND NOT (intern OR "Staff  Augmentation") AND title:director`, `(golang OR rust) AND NOT (intern OR "staff augmentation") AND title:director`},
+		{`a b OR c -d`, `(a AND b OR c AND NOT d)`},
+		{`Title:(vp OR "head of") c++`, `(title:vp OR title:"head of") AND c++`},
+		{`title: company:acme`, `company:acme`},
+	} {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.query, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.query, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{``, `  `, `golang AND`, `(golang`, `golang)`, `"golang`, `salary:100k`, `OR rust`, `!!`} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	q, err := Parse(`golang title:"platform team" company:acme dev* NOT php -title:intern (rust OR zig)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	include, exclude := q.Keywords()
+	if want := []string{"golang", "platform team"}; !slices.Equal(include, want) {
+		t.Errorf("Expected included keywords %v, got %v", want, include)
+	}
+	if want := []string{"php"}; !slices.Equal(exclude, want) {
+		t.Errorf("Expected excluded keywords %v, got %v", want, exclude)
+	}
+
+	q, _ = Parse(`golang OR rust`)
+	if include, exclude := q.Keywords(); include != nil || exclude != nil {
+		t.Errorf("Expected no keywords under OR, got %v and %v", include, exclude)
+	}
+}
@@ -0,0 +1,145 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"job-hunter/internal/crawler"
+)
+
+// Discord's limits on a message, see
+// https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordMaxEmbeds      = 10
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxTotal       = 6000 // Characters in all the embeds of a message
+)
+
+// discordColors are the embed colors of the sections, matching the email.
+var discordColors = map[Section]int{
+	SectionFailedSources: 0x9b2c2c,
+	SectionHealth:        0x744210,
+	SectionNew:           0x38a169,
+	SectionReposted:      0x3182ce,
+	SectionClosed:        0x718096,
+	SectionJobs:          0x2c5282,
+}
+
+// DiscordNotifier posts reports to a Discord webhook as embeds, one per
+// section of each report. Reports with more embeds than a message holds
+// are posted as several messages.
+type DiscordNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// NewDiscordNotifier returns a notifier posting to webhookURL, retrying
+// failed posts according to retry.
+func NewDiscordNotifier(webhookURL string, retry crawler.RetryPolicy) *DiscordNotifier {
+	return &DiscordNotifier{WebhookURL: webhookURL, Client: webhookClient(retry)}
+}
+
+func (n *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+type discordMessage struct {
+	Content         string         `json:"content,omitempty"`
+	Embeds          []discordEmbed `json:"embeds,omitempty"`
+	AllowedMentions struct {
+		Parse []string `json:"parse"`
+	} `json:"allowed_mentions"` // Empty, so job titles can't ping anyone
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+}
+
+func (e discordEmbed) size() int {
+	return len([]rune(e.Title)) + len([]rune(e.Description))
+}
+
+func (n *DiscordNotifier) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	messages := discordReport(date, reports)
+	for i, message := range messages {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, n.Client, n.WebhookURL, body, nil); err != nil {
+			return fmt.Errorf("posting message %d of %d to Discord: %w", i+1, len(messages), err)
+		}
+	}
+	return nil
+}
+
+var discordFormat = chatFormat{
+	escape: strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`).Replace,
+	link: func(text, url string) string {
+		return "[" + text + "](" + strings.NewReplacer("(", "%28", ")", "%29").Replace(url) + ")"
+	},
+}
+
+// discordReport lays out reports as messages of up to discordMaxEmbeds
+// embeds, the first one headed with the date.
+func discordReport(date time.Time, reports []JobReport) []discordMessage {
+	var embeds []discordEmbed
+	for _, report := range reports {
+		name := report.name()
+		for _, section := range chatSections(report, discordFormat) {
+			embeds = append(embeds, discordEmbed{
+				Title:       truncate(name+" - "+section.heading, discordMaxTitle),
+				Description: discordDescription(section.lines),
+				Color:       discordColors[section.section],
+			})
+		}
+	}
+
+	messages := []discordMessage{{Content: "**Job Search Report - " + reportDate(date) + "**"}}
+	size := 0
+	for _, embed := range embeds {
+		last := &messages[len(messages)-1]
+		if len(last.Embeds) == discordMaxEmbeds || size+embed.size() > discordMaxTotal {
+			messages = append(messages, discordMessage{})
+			last, size = &messages[len(messages)-1], 0
+		}
+		last.Embeds = append(last.Embeds, embed)
+		size += embed.size()
+	}
+	for i := range messages {
+		messages[i].AllowedMentions.Parse = []string{}
+	}
+	return messages
+}
+
+// discordDescription joins lines up to the length of an embed's
+// description, counting those left out.
+func discordDescription(lines []string) string {
+	var b strings.Builder
+	size := 0
+	for i, line := range lines {
+		n := len([]rune(line)) + 1
+		if size+n > discordMaxDescription-len("…and 1000 more lines") {
+			fmt.Fprintf(&b, "…and %d more lines", len(lines)-i)
+			break
+		}
+		b.WriteString(line + "\n")
+		size += n
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -2,14 +2,24 @@ package reporter
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/smtp"
-	"os"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
 	"job-hunter/internal/models"
+	"job-hunter/internal/store"
 )
 
 type EmailConfig struct {
@@ -24,9 +34,16 @@ type EmailConfig struct {
 type JobReport struct {
 	Date     time.Time
 	Jobs     []models.Job
-	NewJobs  []models.Job // Jobs that weren't in the previous report
+	NewJobs  []models.Job // Jobs no earlier run had found
 	Location string
 	Title    string
+	Profile  string // Name of the search profile, when the search came from a config file
+
+	RepostedJobs []models.Job // Closed jobs that were found again
+	ClosedJobs   []store.Job  // Jobs that stopped showing up since the last report
+
+	FailedSources []crawler.SourceStatus // Sources that returned an error this run
+	HealthAlarms  []health.Alarm         // Sources that ran but look broken (selector drift)
 }
 
 const emailTemplate = `
@@ -37,18 +54,54 @@ const emailTemplate = `
         body { font-family: Arial, sans-serif; }
         .job { margin: 20px 0; padding: 15px; border: 1px solid #ddd; border-radius: 5px; }
         .new { background-color: #e6ffe6; }
+        .reposted { background-color: #ebf8ff; }
+        .closed { background-color: #f7fafc; color: #718096; }
         .title { color: #2c5282; font-size: 18px; margin-bottom: 5px; }
         .company { color: #4a5568; font-size: 16px; font-weight: bold; margin-bottom: 5px; }
         .source { color: #718096; font-size: 14px; }
         .location { color: #4a5568; font-style: italic; margin-bottom: 5px; }
+        .failed { color: #9b2c2c; background-color: #fff5f5; padding: 10px 15px; border-radius: 5px; }
+        .alarm { color: #744210; background-color: #fffff0; padding: 10px 15px; border-radius: 5px; }
     </style>
 </head>
 <body>
     <h1>Job Search Report - {{.Date.Format "Jan 02, 2006"}}</h1>
+    {{range $i, $report := .Reports}}
+    {{if $i}}<hr>{{end}}
+    {{template "report" $report}}
+    {{end}}
+</body>
+</html>
+{{define "report"}}
+    {{if .Profile}}<h1 class="profile">{{.Profile}}</h1>{{end}}
     <h2>Search Parameters</h2>
     <p>Title: {{.Title}}</p>
     <p>Location: {{.Location}}</p>
-    
+
+    {{if .FailedSources}}
+    <h2>Sources That Failed Today</h2>
+    <div class="failed">
+        <ul>
+        {{range .FailedSources}}
+            <li><strong>{{.Name}}</strong>: {{failure .}}</li>
+        {{end}}
+        </ul>
+        <p>Jobs from these sources are missing from this report.</p>
+    </div>
+    {{end}}
+
+    {{if .HealthAlarms}}
+    <h2>Source Health Alarms</h2>
+    <div class="alarm">
+        <ul>
+        {{range .HealthAlarms}}
+            <li>{{.Message}}</li>
+        {{end}}
+        </ul>
+        <p>These sources may have changed their pages; results from them are likely incomplete.</p>
+    </div>
+    {{end}}
+
     {{if .NewJobs}}
     <h2>New Jobs Since Last Report</h2>
     {{range .NewJobs}}
@@ -56,46 +109,133 @@ const emailTemplate = `
         <div class="title">{{.Title}}</div>
         <div class="company">Company: {{.Company}}</div>
         {{if .Location}}<div class="location">Location: {{.Location}}</div>{{end}}
-        <div class="source">Source: {{.Source}}</div>
+        <div class="source">Source: {{.Source}}{{with alsoOn .}} (also on: {{.}}){{end}}</div>
         {{if .URL}}<a href="{{.URL}}">View Job</a>{{end}}
     </div>
     {{end}}
     {{end}}
 
+    {{if .RepostedJobs}}
+    <h2>Reposted Jobs</h2>
+    <p>These jobs had closed and were posted again.</p>
+    {{range .RepostedJobs}}
+    <div class="job reposted">
+        <div class="title">{{.Title}}</div>
+        <div class="company">Company: {{.Company}}</div>
+        {{if .Location}}<div class="location">Location: {{.Location}}</div>{{end}}
+        <div class="source">Source: {{.Source}}{{with alsoOn .}} (also on: {{.}}){{end}}</div>
+        {{if .URL}}<a href="{{.URL}}">View Job</a>{{end}}
+    </div>
+    {{end}}
+    {{end}}
+
+    {{if .ClosedJobs}}
+    <h2>Closed Since Last Report</h2>
+    {{range .ClosedJobs}}
+    <div class="job closed">
+        <div class="title">{{.Title}}</div>
+        <div class="company">Company: {{.Company}}</div>
+        {{if .Location}}<div class="location">Location: {{.Location}}</div>{{end}}
+        <div class="source">Listed {{.FirstSeen.Format "Jan 02"}} to {{.LastSeen.Format "Jan 02"}}</div>
+    </div>
+    {{end}}
+    {{end}}
+
     <h2>All Jobs</h2>
     {{range .Jobs}}
     <div class="job">
         <div class="title">{{.Title}}</div>
         <div class="company">Company: {{.Company}}</div>
         {{if .Location}}<div class="location">Location: {{.Location}}</div>{{end}}
-        <div class="source">Source: {{.Source}}</div>
+        <div class="source">Source: {{.Source}}{{with alsoOn .}} (also on: {{.}}){{end}}</div>
         {{if .URL}}<a href="{{.URL}}">View Job</a>{{end}}
     </div>
     {{end}}
-</body>
-</html>
+{{end}}
 `
 
-func SendJobReport(config EmailConfig, report JobReport) error {
-	log.Printf("Generating email for %d jobs (%d new)", len(report.Jobs), len(report.NewJobs))
+var templateFuncs = template.FuncMap{
+	"alsoOn":  alsoOn,
+	"failure": failure,
+}
+
+// failure says why a source failed, e.g. "http_status (HTTP 403), blocked
+// by the site". A source our own robots.txt compliance skipped isn't
+// blamed on the site.
+func failure(s crawler.SourceStatus) string {
+	if s.ErrorClass == crawler.ErrorClassRobots {
+		return "skipped by robots.txt"
+	}
+	text := string(s.ErrorClass)
+	if s.StatusCode != 0 {
+		text += fmt.Sprintf(" (HTTP %d)", s.StatusCode)
+	}
+	if s.Blocked && s.ErrorClass != "blocked" {
+		text += ", blocked by the site"
+	}
+	return text
+}
+
+// alsoOn lists the other sources a merged job was found on, e.g.
+// "Indeed, Glassdoor".
+func alsoOn(job models.Job) string {
+	var names []string
+	for _, ref := range job.Sources {
+		if ref.Source != job.Source && !slices.Contains(names, ref.Source) {
+			names = append(names, ref.Source)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderReport generates the HTML body of the report email.
+func renderReport(report JobReport) (*bytes.Buffer, error) {
+	return renderDigest(report.Date, []JobReport{report})
+}
+
+// renderDigest generates the HTML body of an email with several reports.
+func renderDigest(date time.Time, reports []JobReport) (*bytes.Buffer, error) {
 	// Parse template
-	tmpl, err := template.New("email").Parse(emailTemplate)
+	tmpl, err := template.New("email").Funcs(templateFuncs).Parse(emailTemplate)
 	if err != nil {
-		return fmt.Errorf("parsing template: %w", err)
+		return nil, fmt.Errorf("parsing template: %w", err)
 	}
 
 	// Generate HTML
 	var body bytes.Buffer
 	log.Printf("Executing email template")
-	if err := tmpl.Execute(&body, report); err != nil {
-		return fmt.Errorf("executing template: %w", err)
+	data := struct {
+		Date    time.Time
+		Reports []JobReport
+	}{date, reports}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	return &body, nil
+}
+
+func SendJobReport(config EmailConfig, report JobReport) error {
+	return SendDigest(context.Background(), config, report.Date, []JobReport{report})
+}
+
+// SendDigest emails the reports of several searches to one recipient at
+// once, each in its own part of the email.
+func SendDigest(ctx context.Context, config EmailConfig, date time.Time, reports []JobReport) error {
+	var names []string
+	for _, report := range reports {
+		log.Printf("Generating email for %d jobs (%d new, %d reposted, %d closed)", len(report.Jobs), len(report.NewJobs), len(report.RepostedJobs), len(report.ClosedJobs))
+		names = append(names, report.name())
+	}
+	body, err := renderDigest(date, reports)
+	if err != nil {
+		return err
 	}
 
 	// Email headers
 	headers := map[string]string{
 		"From":         config.FromEmail,
 		"To":           config.ToEmail,
-		"Subject":      fmt.Sprintf("Job Search Report for %s - %s", report.Title, report.Date.Format("Jan 02, 2006")),
+		"Subject":      fmt.Sprintf("Job Search Report for %s - %s", strings.Join(names, ", "), reportDate(date)),
 		"MIME-Version": "1.0",
 		"Content-Type": "text/html; charset=UTF-8",
 	}
@@ -109,10 +249,13 @@ func SendJobReport(config EmailConfig, report JobReport) error {
 	message.Write(body.Bytes())
 
 	// Send email
-	auth := smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
-	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+	}
+	addr := net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort))
 	log.Printf("Sending email from %s to %s via %s", config.FromEmail, config.ToEmail, addr)
-	sendErr := smtp.SendMail(addr, auth, config.FromEmail, []string{config.ToEmail}, message.Bytes())
+	sendErr := sendMail(ctx, addr, auth, config.FromEmail, []string{config.ToEmail}, message.Bytes())
 	if sendErr != nil {
 		log.Printf("Email content: %s", body.String())
 		return fmt.Errorf("sending mail: %w", sendErr)
@@ -120,62 +263,62 @@ func SendJobReport(config EmailConfig, report JobReport) error {
 	return nil
 }
 
-func SaveJobsToFile(jobs []models.Job, filename string) error {
-	f, err := os.Create(filename)
+// errMaybeSent marks a send that failed after the whole message reached the
+// server, which may have accepted it. Retrying could deliver it twice.
+var errMaybeSent = errors.New("the message may have been sent")
+
+// sendMail is smtp.SendMail, except that ctx can interrupt it, and once the
+// server accepts the message any later error is ignored; one without the
+// server's answer to the message is wrapped in errMaybeSent.
+func sendMail(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return fmt.Errorf("creating file: %w", err)
+		return err
 	}
-	defer f.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
 
-	for _, job := range jobs {
-		_, err := fmt.Fprintf(f, "%s\t%s\t%s\t%s\n", job.ID, job.Title, job.Company, job.URL)
-		if err != nil {
-			return fmt.Errorf("writing job: %w", err)
+	host, _, _ := net.SplitHostPort(addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
 		}
 	}
-	return nil
-}
-
-func LoadPreviousJobs(filename string) ([]models.Job, error) {
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return nil, nil
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
 	}
-
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
+	if err := c.Mail(from); err != nil {
+		return err
 	}
-
-	var jobs []models.Job
-	lines := bytes.Split(content, []byte("\n"))
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-		parts := bytes.Split(line, []byte("\t"))
-		if len(parts) >= 4 {
-			jobs = append(jobs, models.Job{
-				ID:      string(parts[0]),
-				Title:   string(parts[1]),
-				Company: string(parts[2]),
-				URL:     string(parts[3]),
-			})
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
 		}
 	}
-	return jobs, nil
-}
-
-func FindNewJobs(previous, current []models.Job) []models.Job {
-	seen := make(map[string]bool)
-	for _, job := range previous {
-		seen[job.ID] = true
+	w, err := c.Data()
+	if err != nil {
+		return err
 	}
-
-	var newJobs []models.Job
-	for _, job := range current {
-		if !seen[job.ID] {
-			newJobs = append(newJobs, job)
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		var reply *textproto.Error
+		if !errors.As(err, &reply) {
+			return fmt.Errorf("%w: %w", errMaybeSent, err)
 		}
+		return err
 	}
-	return newJobs
+	// The message is sent; a connection dropped on QUIT doesn't undo that.
+	c.Quit()
+	return nil
 }
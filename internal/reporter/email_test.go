@@ -0,0 +1,132 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
+	"job-hunter/internal/models"
+	"job-hunter/internal/store"
+)
+
+func TestMergedJobs(t *testing.T) {
+	job := models.Job{
+		ID: "indeed:a", Source: "Indeed", Title: "Backend Engineer", Company: "Acme",
+		Sources: []models.SourceRef{
+			{Source: "Indeed", ID: "indeed:a"},
+			{Source: "LinkedIn", ID: "linkedin:1"},
+			{Source: "Glassdoor", ID: "glassdoor:9"},
+		},
+	}
+
+	body, err := renderReport(JobReport{Date: time.Now(), Jobs: []models.Job{job}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(body.String(), "Source: Indeed (also on: LinkedIn, Glassdoor)") {
+		t.Errorf("Expected the other listings to be named, got:\n%s", body.String())
+	}
+}
+
+func TestLifecycleSections(t *testing.T) {
+	listed := time.Date(2025, 4, 2, 8, 0, 0, 0, time.UTC)
+	closed := store.Job{
+		Job:       models.Job{ID: "indeed:b", Source: "Indeed", Title: "Rust Developer", Company: "Hooli"},
+		Status:    store.StatusClosed,
+		FirstSeen: listed,
+		LastSeen:  listed.AddDate(0, 0, 9),
+	}
+	report := JobReport{
+		Date:         time.Now(),
+		RepostedJobs: []models.Job{{ID: "indeed:z", Source: "Indeed", Title: "Go Developer", Company: "Hooli"}},
+		ClosedJobs:   []store.Job{closed},
+	}
+
+	body, err := renderReport(report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{"<h2>Reposted Jobs</h2>", "Go Developer", "<h2>Closed Since Last Report</h2>", "Listed Apr 02 to Apr 11"} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, body.String())
+		}
+	}
+	if strings.Contains(body.String(), "New Jobs Since Last Report") {
+		t.Error("Expected no new jobs section without new jobs")
+	}
+}
+
+func TestDigest(t *testing.T) {
+	reports := []JobReport{
+		{Profile: "go-remote", Title: "Go Developer", Location: "Remote", Jobs: []models.Job{{ID: "1", Title: "Go Developer", Company: "Acme", Source: "Indeed"}}},
+		{Profile: "rust-austin", Title: "Rust Developer", Location: "Austin", Jobs: []models.Job{{ID: "2", Title: "Rust Engineer", Company: "Hooli", Source: "LinkedIn"}}},
+	}
+	body, err := renderDigest(time.Now(), reports)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	html := body.String()
+	if strings.Count(html, "<h1>Job Search Report") != 1 || strings.Count(html, "<h2>Search Parameters</h2>") != 2 {
+		t.Errorf("Expected one email with two reports, got:\n%s", html)
+	}
+	if i, j := strings.Index(html, "go-remote"), strings.Index(html, "Rust Engineer"); i < 0 || j < i {
+		t.Errorf("Expected each profile's jobs under its name, got:\n%s", html)
+	}
+}
+
+func TestFailedSources(t *testing.T) {
+	report := JobReport{
+		Date: time.Now(),
+		FailedSources: []crawler.SourceStatus{
+			{Name: "Glassdoor", ErrorClass: crawler.ErrorClassHTTPStatus, StatusCode: 403, Blocked: true},
+			{Name: "LinkedIn", ErrorClass: crawler.ErrorClassRobots, Blocked: true},
+		},
+	}
+
+	body, err := renderReport(report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{"<h2>Sources That Failed Today</h2>", "<strong>Glassdoor</strong>", string(crawler.ErrorClassHTTPStatus), "(HTTP 403), blocked by the site", "<strong>LinkedIn</strong>: skipped by robots.txt"} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, body.String())
+		}
+	}
+
+	body, err = renderReport(JobReport{Date: time.Now()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(body.String(), "Sources That Failed Today") {
+		t.Error("Expected no failed sources section when every source succeeded")
+	}
+}
+
+func TestHealthAlarms(t *testing.T) {
+	report := JobReport{
+		Date: time.Now(),
+		HealthAlarms: []health.Alarm{
+			{Source: "Indeed", Kind: health.AlarmFieldEmpty, Field: "company", Message: "Indeed: company filled on 5% of jobs, usually 98%"},
+		},
+	}
+
+	body, err := renderReport(report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{"<h2>Source Health Alarms</h2>", "Indeed: company filled on 5% of jobs, usually 98%"} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, body.String())
+		}
+	}
+
+	body, err = renderReport(JobReport{Date: time.Now()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(body.String(), "Source Health Alarms") {
+		t.Error("Expected no health alarms section without alarms")
+	}
+}
@@ -0,0 +1,326 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"job-hunter/internal/crawler"
+)
+
+// Notifier delivers the reports of a run somewhere: an email, a chat
+// channel or a webhook.
+type Notifier interface {
+	// Name identifies the notifier in logs and errors. It must not contain
+	// secrets such as webhook URLs.
+	Name() string
+	// Notify sends the reports of the searches run on date as one message,
+	// or as few as the service's limits allow.
+	Notify(ctx context.Context, date time.Time, reports []JobReport) error
+}
+
+// Section is a part of a report a Channel can be given or spared.
+type Section string
+
+const (
+	SectionNew           Section = "new"            // NewJobs
+	SectionReposted      Section = "reposted"       // RepostedJobs
+	SectionClosed        Section = "closed"         // ClosedJobs
+	SectionJobs          Section = "jobs"           // Jobs, every job the search found
+	SectionFailedSources Section = "failed_sources" // FailedSources
+	SectionHealth        Section = "health"         // HealthAlarms
+)
+
+// Sections lists every section, in the order reports show them.
+var Sections = []Section{SectionFailedSources, SectionHealth, SectionNew, SectionReposted, SectionClosed, SectionJobs}
+
+// ParseSection checks the name of a section.
+func ParseSection(name string) (Section, error) {
+	s := Section(strings.ToLower(strings.TrimSpace(name)))
+	if !slices.Contains(Sections, s) {
+		names := make([]string, len(Sections))
+		for i, s := range Sections {
+			names[i] = string(s)
+		}
+		return "", fmt.Errorf("unknown report section %q (one of: %s)", name, strings.Join(names, ", "))
+	}
+	return s, nil
+}
+
+// Only returns the report with the sections not listed left empty. No
+// sections means all of them.
+func (r JobReport) Only(sections []Section) JobReport {
+	if len(sections) == 0 {
+		return r
+	}
+	if !slices.Contains(sections, SectionNew) {
+		r.NewJobs = nil
+	}
+	if !slices.Contains(sections, SectionReposted) {
+		r.RepostedJobs = nil
+	}
+	if !slices.Contains(sections, SectionClosed) {
+		r.ClosedJobs = nil
+	}
+	if !slices.Contains(sections, SectionJobs) {
+		r.Jobs = nil
+	}
+	if !slices.Contains(sections, SectionFailedSources) {
+		r.FailedSources = nil
+	}
+	if !slices.Contains(sections, SectionHealth) {
+		r.HealthAlarms = nil
+	}
+	return r
+}
+
+// Empty reports whether every section of the report is empty.
+func (r JobReport) Empty() bool {
+	return len(r.NewJobs) == 0 && len(r.RepostedJobs) == 0 && len(r.ClosedJobs) == 0 &&
+		len(r.Jobs) == 0 && len(r.FailedSources) == 0 && len(r.HealthAlarms) == 0
+}
+
+// name is what a report is called in subjects and headings: its profile,
+// or the title searched for.
+func (r JobReport) name() string {
+	if r.Profile != "" {
+		return r.Profile
+	}
+	return r.Title
+}
+
+// Channel is a Notifier that only receives some sections of each report.
+// Reports left with nothing to show are dropped, and when no report is left
+// nothing is sent, so a channel for new jobs stays quiet on days without
+// any.
+type Channel struct {
+	Notifier Notifier
+	Sections []Section // Empty means every section
+}
+
+func (c Channel) Name() string {
+	return c.Notifier.Name()
+}
+
+func (c Channel) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	var kept []JobReport
+	for _, report := range reports {
+		if report = report.Only(c.Sections); !report.Empty() {
+			kept = append(kept, report)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return c.Notifier.Notify(ctx, date, kept)
+}
+
+// EmailNotifier emails reports with SendDigest, retrying network errors and
+// 4xx SMTP replies according to Retry. Failures after the server may have
+// taken the message aren't retried, so it isn't sent twice.
+type EmailNotifier struct {
+	Config EmailConfig
+	Retry  crawler.RetryPolicy
+
+	send func(context.Context, EmailConfig, time.Time, []JobReport) error // SendDigest, or a stand-in in tests
+}
+
+// NewEmailNotifier returns a notifier emailing reports as config says,
+// retrying failed sends according to retry.
+func NewEmailNotifier(config EmailConfig, retry crawler.RetryPolicy) *EmailNotifier {
+	return &EmailNotifier{Config: config, Retry: retry, send: SendDigest}
+}
+
+func (n *EmailNotifier) Name() string {
+	return "email to " + n.Config.ToEmail
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	send := n.send
+	if send == nil {
+		send = SendDigest
+	}
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := send(ctx, n.Config, date, reports)
+		if err == nil || attempt >= n.Retry.MaxRetries || !retryableMail(err) {
+			return err
+		}
+		timer := time.NewTimer(n.Retry.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableMail reports whether a failed send may succeed later: the server
+// couldn't be reached, or answered with a 4xx reply such as a greylisting
+// deferral. 5xx replies, like an unknown recipient, are final, and so is
+// errMaybeSent.
+func retryableMail(err error) bool {
+	if errors.Is(err, errMaybeSent) {
+		return false
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// DefaultEmailRetry is the retry policy of EmailNotifier when none is
+// given. Mail servers defer senders they don't know yet for minutes, so
+// attempts are spaced further apart than webhook posts.
+func DefaultEmailRetry() crawler.RetryPolicy {
+	return crawler.RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  30 * time.Second,
+		MaxDelay:   5 * time.Minute,
+	}
+}
+
+// DefaultWebhookRetry is the retry policy of the webhook notifiers when
+// none is given. Chat services answer bursts with 429 and a Retry-After,
+// which the policy waits out.
+func DefaultWebhookRetry() crawler.RetryPolicy {
+	return crawler.RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// webhookClient returns the HTTP client of a webhook notifier, retrying
+// network errors, 429 and 5xx responses according to retry.
+func webhookClient(retry crawler.RetryPolicy) *http.Client {
+	return &http.Client{
+		Timeout:   2 * time.Minute,
+		Transport: crawler.NewRetryTransport(nil, retry),
+	}
+}
+
+// post sends a JSON body to a webhook. Errors leave out the URL, which
+// holds the webhook's secret for Slack and Discord.
+func post(ctx context.Context, client *http.Client, webhookURL string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// maxChatLines is how many entries of a section chat messages list before
+// referring to the rest by count.
+const maxChatLines = 20
+
+// chatFormat is how a chat service marks up text.
+type chatFormat struct {
+	escape func(string) string
+	link   func(text, url string) string
+}
+
+// chatSection is one non-empty section of a report, laid out as lines of
+// a chat message.
+type chatSection struct {
+	section Section
+	heading string
+	lines   []string
+}
+
+// chatSections lays out the sections of a report for a chat message, in
+// the order of the email.
+func chatSections(report JobReport, f chatFormat) []chatSection {
+	var sections []chatSection
+	add := func(section Section, heading string, n int, line func(i int) string) {
+		if n == 0 {
+			return
+		}
+		lines := make([]string, 0, min(n, maxChatLines)+1)
+		for i := range min(n, maxChatLines) {
+			lines = append(lines, "• "+line(i))
+		}
+		if n > maxChatLines {
+			lines = append(lines, fmt.Sprintf("…and %d more", n-maxChatLines))
+		}
+		sections = append(sections, chatSection{section, heading, lines})
+	}
+
+	add(SectionFailedSources, "Sources That Failed Today", len(report.FailedSources), func(i int) string {
+		s := report.FailedSources[i]
+		return f.escape(s.Name) + ": " + f.escape(failure(s))
+	})
+	add(SectionHealth, "Source Health Alarms", len(report.HealthAlarms), func(i int) string {
+		return f.escape(report.HealthAlarms[i].Message)
+	})
+	jobLine := func(title, company, location, source, jobURL string) string {
+		line := f.escape(title)
+		if jobURL != "" {
+			line = f.link(line, jobURL)
+		}
+		line += " at " + f.escape(company)
+		if location != "" {
+			line += ", " + f.escape(location)
+		}
+		if source != "" {
+			line += " (" + f.escape(source) + ")"
+		}
+		return line
+	}
+	add(SectionNew, "New Jobs Since Last Report", len(report.NewJobs), func(i int) string {
+		job := report.NewJobs[i]
+		return jobLine(job.Title, job.Company, job.Location, job.Source, job.URL)
+	})
+	add(SectionReposted, "Reposted Jobs", len(report.RepostedJobs), func(i int) string {
+		job := report.RepostedJobs[i]
+		return jobLine(job.Title, job.Company, job.Location, job.Source, job.URL)
+	})
+	add(SectionClosed, "Closed Since Last Report", len(report.ClosedJobs), func(i int) string {
+		job := report.ClosedJobs[i]
+		return jobLine(job.Title, job.Company, job.Location, "", "") +
+			fmt.Sprintf(", listed %s to %s", job.FirstSeen.Format("Jan 02"), job.LastSeen.Format("Jan 02"))
+	})
+	add(SectionJobs, "All Jobs", len(report.Jobs), func(i int) string {
+		job := report.Jobs[i]
+		return jobLine(job.Title, job.Company, job.Location, job.Source, job.URL)
+	})
+	return sections
+}
+
+// reportDate is how reports are dated, in email subjects and messages.
+func reportDate(date time.Time) string {
+	return date.Format("Jan 02, 2006")
+}
@@ -0,0 +1,385 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+// webhookServer stands in for a webhook, recording each request's headers
+// and body. Responses are taken from statuses in order, then 204.
+type webhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.headers = append(s.headers, r.Header.Clone())
+		s.bodies = append(s.bodies, body)
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+var testRetry = crawler.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+func testReports(n int) []JobReport {
+	reports := make([]JobReport, n)
+	for i := range reports {
+		reports[i] = JobReport{
+			Profile:  fmt.Sprintf("profile-%d", i+1),
+			Title:    "Go Developer",
+			Location: "Remote",
+			NewJobs:  []models.Job{{ID: "1", Title: "Go <Developer>", Company: "Smith & Sons", Source: "Indeed", URL: "https://example.com/jobs/1"}},
+			Jobs:     []models.Job{{ID: "1", Title: "Go <Developer>", Company: "Smith & Sons", Source: "Indeed", URL: "https://example.com/jobs/1"}},
+			FailedSources: []crawler.SourceStatus{
+				{Name: "LinkedIn", ErrorClass: crawler.ErrorClass("blocked"), StatusCode: 403, Blocked: true},
+			},
+		}
+	}
+	return reports
+}
+
+type recordingNotifier struct {
+	reports [][]JobReport
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	n.reports = append(n.reports, reports)
+	return nil
+}
+
+func TestChannel(t *testing.T) {
+	recorder := &recordingNotifier{}
+	channel := Channel{Notifier: recorder, Sections: []Section{SectionNew, SectionReposted}}
+
+	reports := testReports(2)
+	reports[1].NewJobs = nil
+	if err := channel.Notify(context.Background(), time.Now(), reports); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(recorder.reports) != 1 || len(recorder.reports[0]) != 1 {
+		t.Fatalf("Expected the report without new jobs to be dropped, got %+v", recorder.reports)
+	}
+	got := recorder.reports[0][0]
+	if got.Profile != "profile-1" || len(got.NewJobs) != 1 || got.Jobs != nil || got.FailedSources != nil {
+		t.Errorf("Expected only the new jobs to be sent, got %+v", got)
+	}
+
+	if err := channel.Notify(context.Background(), time.Now(), reports[1:]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(recorder.reports) != 1 {
+		t.Errorf("Expected nothing to be sent without new jobs, got %d messages", len(recorder.reports))
+	}
+
+	if _, err := ParseSection("salaries"); err == nil {
+		t.Error("Expected an error for an unknown section")
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	server := newWebhookServer(t)
+	notifier := NewSlackNotifier(server.URL, testRetry)
+
+	date := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+	if err := notifier.Notify(context.Background(), date, testReports(1)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var message slackMessage
+	if err := json.Unmarshal(server.bodies[0], &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "Job Search Report - Apr 14, 2025: 1 new jobs" {
+		t.Errorf("Expected a summary for notifications, got %q", message.Text)
+	}
+	if message.Blocks[0].Type != "header" {
+		t.Errorf("Expected a header block first, got %+v", message.Blocks[0])
+	}
+	var text []string
+	for _, block := range message.Blocks {
+		if block.Text != nil {
+			text = append(text, block.Text.Text)
+		}
+	}
+	all := strings.Join(text, "\n")
+	for _, want := range []string{
+		"*profile-1*\nGo Developer in Remote",
+		"*New Jobs Since Last Report*\n• <https://example.com/jobs/1|Go &lt;Developer&gt;> at Smith &amp; Sons (Indeed)",
+		"• LinkedIn: blocked (HTTP 403)\n",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Expected the message to contain %q, got:\n%s", want, all)
+		}
+	}
+
+	// Many reports are cut to Slack's limits.
+	message = slackReport(date, testReports(30))
+	if len(message.Blocks) != slackMaxBlocks || message.Blocks[slackMaxBlocks-1].Type != "context" {
+		t.Errorf("Expected %d blocks ending with a note, got %d", slackMaxBlocks, len(message.Blocks))
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	server := newWebhookServer(t)
+	notifier := NewDiscordNotifier(server.URL, testRetry)
+
+	// Four reports with three sections each make 12 embeds, two messages.
+	if err := notifier.Notify(context.Background(), time.Now(), testReports(4)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if server.requests() != 2 {
+		t.Fatalf("Expected 2 messages, got %d", server.requests())
+	}
+	var first, second discordMessage
+	json.Unmarshal(server.bodies[0], &first)
+	json.Unmarshal(server.bodies[1], &second)
+	if !strings.HasPrefix(first.Content, "**Job Search Report - ") || second.Content != "" {
+		t.Errorf("Expected only the first message to be headed, got %q and %q", first.Content, second.Content)
+	}
+	if len(first.Embeds) != discordMaxEmbeds || len(second.Embeds) != 2 {
+		t.Errorf("Expected 10 and 2 embeds, got %d and %d", len(first.Embeds), len(second.Embeds))
+	}
+	embed := first.Embeds[1]
+	if embed.Title != "profile-1 - New Jobs Since Last Report" || embed.Color != discordColors[SectionNew] {
+		t.Errorf("Expected the new jobs embed, got %+v", embed)
+	}
+	if want := `• [Go \<Developer\>](https://example.com/jobs/1) at Smith & Sons (Indeed)`; embed.Description != want {
+		t.Errorf("Expected description %q, got %q", want, embed.Description)
+	}
+	if !strings.Contains(string(server.bodies[0]), `"allowed_mentions":{"parse":[]}`) {
+		t.Errorf("Expected mentions to be disabled, got %s", server.bodies[0])
+	}
+
+	long := make([]string, 300)
+	for i := range long {
+		long[i] = strings.Repeat("x", 30)
+	}
+	if description := discordDescription(long); len([]rune(description)) > discordMaxDescription || !strings.HasSuffix(description, "more lines") {
+		t.Errorf("Expected a long description to be cut, got %d characters", len([]rune(description)))
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server := newWebhookServer(t)
+	notifier := NewWebhookNotifier(server.URL, "s3cret", testRetry)
+
+	date := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+	reports := testReports(1)
+	reports[0] = reports[0].Only([]Section{SectionNew})
+	if err := notifier.Notify(context.Background(), date, reports); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	header, body := server.headers[0], server.bodies[0]
+	timestamp := header.Get(TimestampHeader)
+	if timestamp == "" || header.Get(SignatureHeader) != Sign("s3cret", timestamp, body) {
+		t.Errorf("Expected a valid signature, got %q for timestamp %q", header.Get(SignatureHeader), timestamp)
+	}
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON body, got %q", header.Get("Content-Type"))
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !payload.Date.Equal(date) || len(payload.Reports) != 1 || len(payload.Reports[0].NewJobs) != 1 {
+		t.Errorf("Expected the report with its new jobs, got %+v", payload)
+	}
+	if strings.Contains(string(body), `"jobs"`) || strings.Contains(string(body), "failed_sources") {
+		t.Errorf("Expected the sections left out to be omitted, got %s", body)
+	}
+
+	notifier.Secret = ""
+	if err := notifier.Notify(context.Background(), date, reports); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := server.headers[1].Get(SignatureHeader); got != "" {
+		t.Errorf("Expected no signature without a secret, got %q", got)
+	}
+}
+
+func TestNotifierRetry(t *testing.T) {
+	server := newWebhookServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	notifier := NewSlackNotifier(server.URL, testRetry)
+	if err := notifier.Notify(context.Background(), time.Now(), testReports(1)); err != nil {
+		t.Errorf("Expected the post to succeed on the third attempt, got %v", err)
+	}
+	if server.requests() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", server.requests())
+	}
+
+	server = newWebhookServer(t, http.StatusBadRequest)
+	notifier = NewSlackNotifier(server.URL, testRetry)
+	err := notifier.Notify(context.Background(), time.Now(), testReports(1))
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("Expected an HTTP 400 error, got %v", err)
+	}
+	if server.requests() != 1 {
+		t.Errorf("Expected a client error not to be retried, got %d attempts", server.requests())
+	}
+
+	// The URL holds the webhook's secret and is kept out of errors.
+	server.Close()
+	err = notifier.Notify(context.Background(), time.Now(), testReports(1))
+	if err == nil || strings.Contains(err.Error(), server.URL) {
+		t.Errorf("Expected an error without the URL, got %v", err)
+	}
+}
+
+func TestEmailNotifierRetry(t *testing.T) {
+	var sent int
+	replies := []error{&textproto.Error{Code: 451, Msg: "Try again later"}}
+	notifier := NewEmailNotifier(EmailConfig{ToEmail: "me@example.com"}, testRetry)
+	notifier.send = func(context.Context, EmailConfig, time.Time, []JobReport) error {
+		sent++
+		if len(replies) == 0 {
+			return nil
+		}
+		err := replies[0]
+		replies = replies[1:]
+		return fmt.Errorf("sending mail: %w", err)
+	}
+
+	if err := notifier.Notify(context.Background(), time.Now(), testReports(1)); err != nil {
+		t.Errorf("Expected the email to be sent on the second attempt, got %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("Expected 2 attempts, got %d", sent)
+	}
+
+	sent, replies = 0, []error{&textproto.Error{Code: 550, Msg: "No such user"}}
+	if err := notifier.Notify(context.Background(), time.Now(), testReports(1)); err == nil || sent != 1 {
+		t.Errorf("Expected a 5xx reply to fail without a retry, got %v after %d attempts", err, sent)
+	}
+
+	sent = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := notifier.Notify(ctx, time.Now(), testReports(1)); !errors.Is(err, context.Canceled) || sent != 0 {
+		t.Errorf("Expected a canceled context to stop the email, got %v after %d attempts", err, sent)
+	}
+}
+
+// newSMTPServer stands in for a mail server without TLS or AUTH. It drops
+// the connection instead of answering dropOn, "QUIT" or the end of the
+// message's DATA, and returns the config to reach it and a count of the
+// messages it received.
+func newSMTPServer(t *testing.T, dropOn string) (EmailConfig, *atomic.Int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var received atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				text := textproto.NewConn(conn)
+				text.PrintfLine("220 localhost ESMTP")
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+					switch verb {
+					case "EHLO", "HELO", "MAIL", "RCPT":
+						text.PrintfLine("250 OK")
+					case "DATA":
+						text.PrintfLine("354 Go ahead")
+						if _, err := text.ReadDotBytes(); err != nil {
+							return
+						}
+						received.Add(1)
+						if dropOn == "DATA" {
+							return
+						}
+						text.PrintfLine("250 Queued")
+					case "QUIT":
+						if dropOn == "QUIT" {
+							return
+						}
+						text.PrintfLine("221 Bye")
+					default:
+						text.PrintfLine("502 Not implemented")
+					}
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return EmailConfig{SMTPHost: host, SMTPPort: n, FromEmail: "jobs@example.com", ToEmail: "me@example.com"}, &received
+}
+
+func TestEmailNotifierSentOnce(t *testing.T) {
+	// A connection dropped after the server queued the message isn't a
+	// failure.
+	config, received := newSMTPServer(t, "QUIT")
+	if err := NewEmailNotifier(config, testRetry).Notify(context.Background(), time.Now(), testReports(1)); err != nil {
+		t.Errorf("Expected the queued email to count as sent, got %v", err)
+	}
+	if received.Load() != 1 {
+		t.Errorf("Expected 1 email, got %d", received.Load())
+	}
+
+	// Without an answer to the message, it may have been queued, so it
+	// isn't sent again.
+	config, received = newSMTPServer(t, "DATA")
+	err := NewEmailNotifier(config, testRetry).Notify(context.Background(), time.Now(), testReports(1))
+	if !errors.Is(err, errMaybeSent) {
+		t.Errorf("Expected errMaybeSent, got %v", err)
+	}
+	if received.Load() != 1 {
+		t.Errorf("Expected 1 email, got %d", received.Load())
+	}
+}
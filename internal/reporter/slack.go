@@ -0,0 +1,119 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"job-hunter/internal/crawler"
+)
+
+// Slack's limits on a message, see https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxBlocks = 50
+	slackMaxText   = 3000 // Characters in the text of a section block
+)
+
+// SlackNotifier posts reports to a Slack incoming webhook as Block Kit
+// messages.
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// NewSlackNotifier returns a notifier posting to webhookURL, retrying
+// failed posts according to retry.
+func NewSlackNotifier(webhookURL string, retry crawler.RetryPolicy) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL, Client: webhookClient(retry)}
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // Shown in notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	body, err := json.Marshal(slackReport(date, reports))
+	if err != nil {
+		return err
+	}
+	if err := post(ctx, n.Client, n.WebhookURL, body, nil); err != nil {
+		return fmt.Errorf("posting to Slack: %w", err)
+	}
+	return nil
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var slackFormat = chatFormat{
+	escape: slackEscaper.Replace,
+	link: func(text, url string) string {
+		return "<" + strings.NewReplacer("|", "%7C", ">", "%3E").Replace(url) + "|" + text + ">"
+	},
+}
+
+// slackReport lays out reports as one message: a header, then each report
+// under its name, split into section blocks short enough for Slack.
+func slackReport(date time.Time, reports []JobReport) slackMessage {
+	title := "Job Search Report - " + reportDate(date)
+	var newJobs int
+	blocks := []slackBlock{{Type: "header", Text: &slackText{"plain_text", title}}}
+	for i, report := range reports {
+		newJobs += len(report.NewJobs)
+		if i > 0 {
+			blocks = append(blocks, slackBlock{Type: "divider"})
+		}
+		heading := "*" + slackFormat.escape(report.name()) + "*"
+		if report.Profile != "" {
+			heading += "\n" + slackFormat.escape(report.Title)
+		}
+		if report.Location != "" {
+			heading += " in " + slackFormat.escape(report.Location)
+		}
+		blocks = append(blocks, slackSection(heading))
+
+		for _, section := range chatSections(report, slackFormat) {
+			text := "*" + section.heading + "*"
+			for _, line := range section.lines {
+				if len(text)+1+len(line) > slackMaxText {
+					blocks = append(blocks, slackSection(text))
+					text = ""
+				}
+				if text != "" {
+					text += "\n"
+				}
+				text += line
+			}
+			blocks = append(blocks, slackSection(text))
+		}
+	}
+	if len(blocks) > slackMaxBlocks {
+		blocks = append(blocks[:slackMaxBlocks-1], slackBlock{
+			Type:     "context",
+			Elements: []slackText{{"mrkdwn", "The rest of the report was left out to fit Slack's limits."}},
+		})
+	}
+	return slackMessage{Text: fmt.Sprintf("%s: %d new jobs", title, newJobs), Blocks: blocks}
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{"mrkdwn", text}}
+}
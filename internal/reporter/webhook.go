@@ -0,0 +1,108 @@
+package reporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
+	"job-hunter/internal/models"
+	"job-hunter/internal/store"
+)
+
+// Headers of the requests WebhookNotifier makes.
+const (
+	TimestampHeader = "X-Job-Hunter-Timestamp"
+	SignatureHeader = "X-Job-Hunter-Signature"
+)
+
+// WebhookNotifier posts reports as JSON to any URL. With a secret, each
+// request is signed: SignatureHeader holds "sha256=" and the hex HMAC-SHA256
+// of the TimestampHeader value, a dot and the body, keyed with the secret.
+// Receivers should recompute it and reject old timestamps, so a captured
+// request can't be replayed.
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier posting to url, signing requests
+// with secret unless it is empty and retrying failed posts according to
+// retry.
+func NewWebhookNotifier(url, secret string, retry crawler.RetryPolicy) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, Client: webhookClient(retry)}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// WebhookPayload is the body of the requests WebhookNotifier makes.
+type WebhookPayload struct {
+	Date    time.Time       `json:"date"`
+	Reports []WebhookReport `json:"reports"`
+}
+
+// WebhookReport is a JobReport as WebhookNotifier sends it. Sections the
+// channel doesn't receive are left out.
+type WebhookReport struct {
+	Profile  string `json:"profile,omitempty"`
+	Title    string `json:"title"`
+	Location string `json:"location"`
+
+	NewJobs       []models.Job           `json:"new_jobs,omitempty"`
+	RepostedJobs  []models.Job           `json:"reposted_jobs,omitempty"`
+	ClosedJobs    []store.Job            `json:"closed_jobs,omitempty"`
+	Jobs          []models.Job           `json:"jobs,omitempty"`
+	FailedSources []crawler.SourceStatus `json:"failed_sources,omitempty"`
+	HealthAlarms  []health.Alarm         `json:"health_alarms,omitempty"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, date time.Time, reports []JobReport) error {
+	payload := WebhookPayload{Date: date, Reports: make([]WebhookReport, len(reports))}
+	for i, r := range reports {
+		payload.Reports[i] = WebhookReport{
+			Profile:       r.Profile,
+			Title:         r.Title,
+			Location:      r.Location,
+			NewJobs:       r.NewJobs,
+			RepostedJobs:  r.RepostedJobs,
+			ClosedJobs:    r.ClosedJobs,
+			Jobs:          r.Jobs,
+			FailedSources: r.FailedSources,
+			HealthAlarms:  r.HealthAlarms,
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header.Set(TimestampHeader, timestamp)
+		header.Set(SignatureHeader, Sign(n.Secret, timestamp, body))
+	}
+	if err := post(ctx, n.Client, n.URL, body, header); err != nil {
+		return fmt.Errorf("posting to webhook: %w", err)
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value of a webhook request.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
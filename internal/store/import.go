@@ -0,0 +1,108 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+// ImportPreviousJobs adds the jobs listed in a previous_jobs.txt file, as
+// written by earlier versions, and returns how many were added. They are
+// dated by the file's modification time and belong to no run, but keep
+// the source named by their ID. Jobs the store already has
+// are left alone, so importing twice is harmless.
+//
+// Jobs saved under the old title-based IDs are matched by RecordRun when
+// a search finds them again, and then take their current ID. Those never
+// found again are closed like any other job, see closeMissing.
+func (s *Store) ImportPreviousJobs(ctx context.Context, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	jobs, err := readPreviousJobs(path)
+	if err != nil {
+		return 0, err
+	}
+	seen := formatTime(info.ModTime())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, job := range jobs {
+		source := importedSource(job.ID)
+		res, err := tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO jobs (id, title, company, url, source, first_seen, last_seen) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			job.ID, job.Title, job.Company, job.URL, source, seen, seen)
+		if err != nil {
+			return 0, fmt.Errorf("importing job %s: %w", job.ID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO listings (id, job_id, source, url, legacy_key) VALUES (?, ?, ?, ?, ?)`,
+			job.ID, job.ID, source, job.URL, crawler.NormalizeLegacyJobID(job.ID))
+		if err != nil {
+			return 0, fmt.Errorf("importing job %s: %w", job.ID, err)
+		}
+		added++
+	}
+	return added, tx.Commit()
+}
+
+// importedSource returns the registered source an imported ID starts with,
+// as in "indeed-Go+Developer-Hooli" or "indeed:0a9b8c7d", or "" if it
+// names none.
+func importedSource(id string) string {
+	prefix, _, _ := strings.Cut(id, ":")
+	prefix, _, _ = strings.Cut(prefix, "-")
+	for _, name := range crawler.Registered() {
+		if strings.EqualFold(name, prefix) {
+			return name
+		}
+	}
+	return ""
+}
+
+// readPreviousJobs parses a previous_jobs.txt file: one tab-separated
+// line of ID, title, company and URL per listing.
+func readPreviousJobs(path string) ([]models.Job, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	var jobs []models.Job
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		parts := bytes.Split(line, []byte("\t"))
+		if len(parts) >= 4 {
+			jobs = append(jobs, models.Job{
+				ID:      string(parts[0]),
+				Title:   string(parts[1]),
+				Company: string(parts[2]),
+				URL:     string(parts[3]),
+			})
+			continue
+		}
+		// Older files wrote titles with embedded newlines, spreading a job
+		// over several lines. The ID, which never contains whitespace,
+		// still starts its first line; the rest is lost.
+		if id := string(parts[0]); id != "" && !bytes.ContainsAny(parts[0], " \r") {
+			jobs = append(jobs, models.Job{ID: id})
+		}
+	}
+	return jobs, nil
+}
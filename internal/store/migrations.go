@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order, each in its own transaction. The schema
+// version is the number applied so far, kept in PRAGMA user_version. Never
+// edit a migration once released; append a new one.
+var migrations = []string{
+	// 1: runs, jobs and the listings that point at them.
+	`
+CREATE TABLE runs (
+	id          INTEGER PRIMARY KEY,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT NOT NULL DEFAULT '',
+	title       TEXT NOT NULL DEFAULT '',
+	location    TEXT NOT NULL DEFAULT '',
+	job_count   INTEGER NOT NULL DEFAULT 0,
+	new_count   INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE jobs (
+	id               TEXT PRIMARY KEY,
+	title            TEXT NOT NULL DEFAULT '',
+	company          TEXT NOT NULL DEFAULT '',
+	location         TEXT NOT NULL DEFAULT '',
+	description      TEXT NOT NULL DEFAULT '',
+	description_html TEXT NOT NULL DEFAULT '',
+	url              TEXT NOT NULL DEFAULT '',
+	source           TEXT NOT NULL DEFAULT '',
+	salary           TEXT NOT NULL DEFAULT '',
+	salary_min       REAL NOT NULL DEFAULT 0,
+	salary_max       REAL NOT NULL DEFAULT 0,
+	salary_currency  TEXT NOT NULL DEFAULT '',
+	salary_period    TEXT NOT NULL DEFAULT '',
+	employment_type  TEXT NOT NULL DEFAULT '',
+	posted_date      TEXT NOT NULL DEFAULT '',
+	enriched_at      TEXT NOT NULL DEFAULT '',
+	first_seen       TEXT NOT NULL,
+	last_seen        TEXT NOT NULL,
+	first_run_id     INTEGER REFERENCES runs (id),
+	last_run_id      INTEGER REFERENCES runs (id)
+);
+
+CREATE INDEX jobs_last_seen ON jobs (last_seen);
+
+-- One row per listing ID a job has been seen under. legacy_key holds the
+-- normalized form of IDs imported from previous_jobs.txt.
+CREATE TABLE listings (
+	id         TEXT PRIMARY KEY,
+	job_id     TEXT NOT NULL REFERENCES jobs (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	source     TEXT NOT NULL DEFAULT '',
+	url        TEXT NOT NULL DEFAULT '',
+	legacy_key TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX listings_job_id ON listings (job_id);
+CREATE INDEX listings_legacy_key ON listings (legacy_key) WHERE legacy_key != '';
+
+CREATE TABLE run_jobs (
+	run_id INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	job_id TEXT NOT NULL REFERENCES jobs (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	is_new INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (run_id, job_id)
+);
+
+CREATE INDEX run_jobs_job_id ON run_jobs (job_id);
+`,
+	// 2: job lifecycle. Jobs close after going missing from runs of their
+	// search in which their sources succeeded, and can be reposted.
+	`
+ALTER TABLE runs ADD COLUMN query_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN reposted_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE runs ADD COLUMN closed_count INTEGER NOT NULL DEFAULT 0;
+UPDATE runs SET query_key = lower(trim(title)) || '|' || lower(trim(location));
+CREATE INDEX runs_query_key ON runs (query_key);
+
+CREATE TABLE run_sources (
+	run_id    INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	source    TEXT NOT NULL,
+	ok        INTEGER NOT NULL,
+	job_count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (run_id, source)
+);
+
+ALTER TABLE jobs ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE jobs ADD COLUMN misses INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN closed_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN reposted_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN repost_count INTEGER NOT NULL DEFAULT 0;
+-- Set when a job closes, to recognize it if it comes back under a new ID.
+ALTER TABLE jobs ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+CREATE INDEX jobs_closed_fingerprint ON jobs (fingerprint) WHERE status = 'closed';
+`,
+	// 3: finished asynchronous searches, kept for the API server.
+	`
+CREATE TABLE searches (
+	id          TEXT PRIMARY KEY,
+	finished_at TEXT NOT NULL,
+	state       TEXT NOT NULL,
+	results     TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX searches_finished_at ON searches (finished_at);
+`,
+}
+
+// migrate brings the schema up to date.
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this program (%d)", version, len(migrations))
+	}
+	for v := version; v < len(migrations); v++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[v]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %d: %w", v+1, err)
+		}
+		// PRAGMA doesn't take bound parameters.
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", v+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %d: %w", v+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("applying migration %d: %w", v+1, err)
+		}
+	}
+	return nil
+}
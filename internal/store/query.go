@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+// ErrInvalidFilter is returned, wrapped, for a filter with an unknown sort,
+// status or a malformed cursor.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// JobFilter selects and orders stored jobs. Zero fields don't filter.
+type JobFilter struct {
+	Sources  []string // Jobs with a listing on any of these sources
+	Company  string   // Substring of the company, ignoring case
+	Location string   // Substring of the location, ignoring case
+	Keyword  string   // Substring of the title, company or description, ignoring case
+	Status   string   // StatusOpen or StatusClosed
+
+	PostedAfter     time.Time // Inclusive
+	PostedBefore    time.Time // Exclusive
+	FirstSeenAfter  time.Time // Inclusive
+	FirstSeenBefore time.Time // Exclusive
+
+	// SalaryMin and SalaryMax keep jobs whose stated salary range, per
+	// year, overlaps them. Jobs without a machine-readable salary, or paid
+	// per an unknown period, are left out. Amounts aren't converted between
+	// currencies, so set SalaryCurrency to compare like with like.
+	SalaryMin float64
+	SalaryMax float64
+	// SalaryCurrency keeps jobs paid in this ISO 4217 currency.
+	SalaryCurrency string
+
+	// Sort is one of the SortFields, prefixed with "-" for descending
+	// order. Empty means "-last_seen".
+	Sort string
+	// Cursor continues from the page that returned it.
+	Cursor string
+	// Limit caps the page size. Zero means DefaultLimit.
+	Limit int
+}
+
+// DefaultLimit is the page size of a JobFilter without a Limit.
+const DefaultLimit = 50
+
+// sortColumns maps the sortable fields to their SQL expressions.
+var sortColumns = map[string]string{
+	"last_seen":  "j.last_seen",
+	"first_seen": "j.first_seen",
+	"posted":     "j.posted_date",
+	"salary":     "COALESCE(NULLIF(j.salary_max, 0), j.salary_min) * " + yearlyFactor,
+	"title":      "j.title COLLATE NOCASE",
+	"company":    "j.company COLLATE NOCASE",
+}
+
+// yearlyFactor is the SQL expression of crawler.PeriodsPerYear for a job's
+// salary period, 0 for an unknown one.
+var yearlyFactor = func() string {
+	periods := slices.Sorted(maps.Keys(crawler.PeriodsPerYear))
+	var b strings.Builder
+	b.WriteString("(CASE j.salary_period")
+	for _, period := range periods {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %g", period, crawler.PeriodsPerYear[period])
+	}
+	b.WriteString(" ELSE 0 END)")
+	return b.String()
+}()
+
+// SortFields lists the fields a JobFilter can sort by.
+var SortFields = []string{"last_seen", "first_seen", "posted", "salary", "title", "company"}
+
+// JobPage is one page of the jobs matching a filter.
+type JobPage struct {
+	Jobs []Job `json:"jobs"`
+	// Total counts every matching job, not just this page.
+	Total int `json:"total"`
+	// NextCursor fetches the next page. It is empty on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position after the last job of a page: its sort value and
+// ID, the tie-breaker.
+type cursor struct {
+	Value any    `json:"v"`
+	ID    string `json:"id"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.ID == "" {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// Jobs returns a page of the stored jobs matching filter.
+func (s *Store) Jobs(ctx context.Context, filter JobFilter) (JobPage, error) {
+	sortField, desc := strings.TrimPrefix(filter.Sort, "-"), strings.HasPrefix(filter.Sort, "-")
+	if filter.Sort == "" {
+		sortField, desc = "last_seen", true
+	}
+	sortExpr, ok := sortColumns[sortField]
+	if !ok {
+		return JobPage{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, filter.Sort)
+	}
+	if filter.Status != "" && filter.Status != StatusOpen && filter.Status != StatusClosed {
+		return JobPage{}, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, filter.Status)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	where, args := filter.conditions()
+	var page JobPage
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs j`+whereClause(where), args...).Scan(&page.Total)
+	if err != nil {
+		return JobPage{}, err
+	}
+
+	op, order := ">", "ASC"
+	if desc {
+		op, order = "<", "DESC"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return JobPage{}, err
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND j.id %[2]s ?))", sortExpr, op))
+		args = append(args, c.Value, c.Value, c.ID)
+	}
+
+	// One extra row tells whether there is a next page.
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobColumns+`, `+sortExpr+` FROM jobs j`+whereClause(where)+
+		fmt.Sprintf(` ORDER BY %s %s, j.id %s LIMIT ?`, sortExpr, order, order), append(args, limit+1)...)
+	if err != nil {
+		return JobPage{}, err
+	}
+	defer rows.Close()
+	var last any
+	for rows.Next() {
+		var value any
+		job, err := scanJob(rows, &value)
+		if err != nil {
+			return JobPage{}, err
+		}
+		if len(page.Jobs) == limit {
+			page.NextCursor = cursor{Value: last, ID: page.Jobs[limit-1].ID}.encode()
+			break
+		}
+		page.Jobs = append(page.Jobs, job)
+		last = value
+	}
+	if err := rows.Err(); err != nil {
+		return JobPage{}, err
+	}
+	rows.Close()
+
+	if err := s.loadSources(ctx, page.Jobs); err != nil {
+		return JobPage{}, err
+	}
+	if page.Jobs == nil {
+		page.Jobs = []Job{}
+	}
+	return page, nil
+}
+
+// conditions returns the SQL conditions and arguments of the filter's
+// criteria.
+func (f JobFilter) conditions() ([]string, []any) {
+	var where []string
+	var args []any
+	if len(f.Sources) > 0 {
+		where = append(where, `EXISTS (SELECT 1 FROM listings l WHERE l.job_id = j.id AND l.source COLLATE NOCASE IN (`+
+			placeholders(len(f.Sources))+`))`)
+		for _, source := range f.Sources {
+			args = append(args, source)
+		}
+	}
+	if f.Company != "" {
+		where = append(where, `j.company LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(f.Company))
+	}
+	if f.Location != "" {
+		where = append(where, `j.location LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(f.Location))
+	}
+	if f.Keyword != "" {
+		where = append(where, `(j.title LIKE ? ESCAPE '\' OR j.company LIKE ? ESCAPE '\' OR j.description LIKE ? ESCAPE '\')`)
+		pattern := likePattern(f.Keyword)
+		args = append(args, pattern, pattern, pattern)
+	}
+	if f.Status != "" {
+		where = append(where, `j.status = ?`)
+		args = append(args, f.Status)
+	}
+	if !f.PostedAfter.IsZero() {
+		where = append(where, `j.posted_date >= ?`)
+		args = append(args, formatTime(f.PostedAfter))
+	}
+	if !f.PostedBefore.IsZero() {
+		where = append(where, `j.posted_date != '' AND j.posted_date < ?`)
+		args = append(args, formatTime(f.PostedBefore))
+	}
+	if !f.FirstSeenAfter.IsZero() {
+		where = append(where, `j.first_seen >= ?`)
+		args = append(args, formatTime(f.FirstSeenAfter))
+	}
+	if !f.FirstSeenBefore.IsZero() {
+		where = append(where, `j.first_seen < ?`)
+		args = append(args, formatTime(f.FirstSeenBefore))
+	}
+	if f.SalaryMin > 0 {
+		where = append(where, sortColumns["salary"]+` >= ?`)
+		args = append(args, f.SalaryMin)
+	}
+	if f.SalaryMax > 0 {
+		low := `COALESCE(NULLIF(j.salary_min, 0), j.salary_max) * ` + yearlyFactor
+		where = append(where, low+` > 0 AND `+low+` <= ?`)
+		args = append(args, f.SalaryMax)
+	}
+	if f.SalaryCurrency != "" {
+		where = append(where, `j.salary_currency = ? COLLATE NOCASE`)
+		args = append(args, f.SalaryCurrency)
+	}
+	return where, args
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// likePattern matches s anywhere, with LIKE's wildcards in s escaped.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
+// loadSources fills in the Sources of each job with several listings.
+func (s *Store) loadSources(ctx context.Context, jobs []Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(jobs))
+	args := make([]any, len(jobs))
+	for i, job := range jobs {
+		index[job.ID] = i
+		args[i] = job.ID
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT job_id, id, source, url FROM listings WHERE job_id IN (`+placeholders(len(jobs))+`) ORDER BY job_id = id DESC, rowid`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	refs := make(map[string][]models.SourceRef)
+	for rows.Next() {
+		var jobID string
+		var ref models.SourceRef
+		if err := rows.Scan(&jobID, &ref.ID, &ref.Source, &ref.URL); err != nil {
+			return err
+		}
+		refs[jobID] = append(refs[jobID], ref)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Like a freshly merged job, only jobs with several listings list them.
+	for id, list := range refs {
+		if len(list) > 1 {
+			jobs[index[id]].Sources = list
+		}
+	}
+	return nil
+}
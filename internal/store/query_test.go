@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+	day := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+
+	// Each job is first seen a day after the one before it.
+	jobs := []models.Job{
+		{ID: "indeed:a", Source: "Indeed", Title: "Go Developer", Company: "Hooli", Location: "Austin, TX", SalaryMin: 120000, SalaryMax: 150000},
+		{ID: "linkedin:1", Source: "LinkedIn", Title: "Backend Engineer", Company: "Acme", Location: "Remote", Description: "Go and Postgres", PostedDate: day},
+		{ID: "indeed:b", Source: "Indeed", Title: "Rust Developer", Company: "Hooli", Location: "Denver, CO", SalaryMin: 90000},
+		{ID: "glassdoor:9", Source: "Glassdoor", Title: "100% Remote SRE", Company: "Initech", Sources: []models.SourceRef{
+			{Source: "Glassdoor", ID: "glassdoor:9"},
+			{Source: "Indeed", ID: "indeed:c"},
+		}},
+	}
+	for i, job := range jobs {
+		seen := day.AddDate(0, 0, i)
+		s.now = func() time.Time { return seen }
+		if _, _, err := s.RecordRun(ctx, crawler.JobSearchParams{Title: job.Title}, seen, result(job)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(page JobPage) []string {
+		var ids []string
+		for _, job := range page.Jobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+	for _, tt := range []struct {
+		name   string
+		filter JobFilter
+		want   []string
+	}{
+		{"default newest first", JobFilter{}, []string{"glassdoor:9", "indeed:b", "linkedin:1", "indeed:a"}},
+		{"source of any listing", JobFilter{Sources: []string{"indeed"}}, []string{"glassdoor:9", "indeed:b", "indeed:a"}},
+		{"company", JobFilter{Company: "hoo", Sort: "title"}, []string{"indeed:a", "indeed:b"}},
+		{"location", JobFilter{Location: "remote"}, []string{"linkedin:1"}},
+		{"keyword in description", JobFilter{Keyword: "postgres"}, []string{"linkedin:1"}},
+		{"keyword with a LIKE wildcard", JobFilter{Keyword: "100%"}, []string{"glassdoor:9"}},
+		{"posted range", JobFilter{PostedAfter: day, PostedBefore: day.AddDate(0, 0, 1)}, []string{"linkedin:1"}},
+		{"first seen range", JobFilter{FirstSeenAfter: day.AddDate(0, 0, 1), FirstSeenBefore: day.AddDate(0, 0, 3), Sort: "first_seen"}, []string{"linkedin:1", "indeed:b"}},
+		{"salary range", JobFilter{SalaryMin: 100000, SalaryMax: 130000}, []string{"indeed:a"}},
+		{"salary sort", JobFilter{Sort: "-salary", SalaryMin: 1}, []string{"indeed:a", "indeed:b"}},
+		{"status", JobFilter{Status: StatusClosed}, nil},
+	} {
+		page, err := s.Jobs(ctx, tt.filter)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		if got := ids(page); !slices.Equal(got, tt.want) || page.Total != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v (total %d)", tt.name, tt.want, got, page.Total)
+		}
+	}
+
+	page, err := s.Jobs(ctx, JobFilter{Sources: []string{"Indeed"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Jobs[0].Sources) != 2 || page.Jobs[0].Status != StatusOpen {
+		t.Errorf("Expected the merged job's listings and status, got %+v", page.Jobs[0])
+	}
+
+	// Paging through every sort visits each job once, in order.
+	for _, sort := range []string{"-last_seen", "title", "-salary", "posted", "company"} {
+		var got []string
+		filter := JobFilter{Sort: sort, Limit: 3}
+		for {
+			page, err := s.Jobs(ctx, filter)
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", sort, err)
+			}
+			if page.Total != 4 {
+				t.Errorf("%s: expected a total of 4 on every page, got %d", sort, page.Total)
+			}
+			got = append(got, ids(page)...)
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+		all, _ := s.Jobs(ctx, JobFilter{Sort: sort})
+		if !slices.Equal(got, ids(all)) {
+			t.Errorf("%s: expected pages to add up to %v, got %v", sort, ids(all), got)
+		}
+	}
+
+	for _, filter := range []JobFilter{{Sort: "pay"}, {Status: "gone"}, {Cursor: "nope"}} {
+		if _, err := s.Jobs(ctx, filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %+v, got %v", filter, err)
+		}
+	}
+}
+
+func TestJobsSalaryPeriod(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+
+	// $50 an hour is $104,000 a year.
+	jobs := result(
+		models.Job{ID: "indeed:hourly", Source: "Indeed", Title: "Go Contractor", SalaryMin: 50, SalaryMax: 60, SalaryCurrency: "USD", SalaryPeriod: "hour"},
+		models.Job{ID: "indeed:yearly", Source: "Indeed", Title: "Go Developer", SalaryMin: 90000, SalaryMax: 95000, SalaryCurrency: "USD", SalaryPeriod: "year"},
+		models.Job{ID: "indeed:euros", Source: "Indeed", Title: "Go Entwickler", SalaryMin: 110000, SalaryCurrency: "EUR"},
+		models.Job{ID: "indeed:upto", Source: "Indeed", Title: "Go Engineer", SalaryMax: 85000, SalaryCurrency: "USD", SalaryPeriod: "year"},
+		models.Job{ID: "indeed:shift", Source: "Indeed", Title: "Go Shifts", SalaryMin: 500, SalaryPeriod: "shift"},
+	)
+	if _, _, err := s.RecordRun(ctx, crawler.JobSearchParams{Title: "go"}, time.Now(), jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		filter JobFilter
+		want   []string
+	}{
+		{"hourly pay above a yearly minimum", JobFilter{SalaryMin: 100000, SalaryCurrency: "usd"}, []string{"indeed:hourly"}},
+		{"hourly pay above a yearly maximum, and pay up to it", JobFilter{SalaryMax: 100000, SalaryCurrency: "USD"}, []string{"indeed:yearly", "indeed:upto"}},
+		{"unconverted currencies", JobFilter{SalaryMin: 100000, SalaryMax: 110000}, []string{"indeed:hourly", "indeed:euros"}},
+		{"hourly pay within a yearly range", JobFilter{SalaryMin: 100000, SalaryMax: 110000, SalaryCurrency: "USD"}, []string{"indeed:hourly"}},
+		{"yearly sort", JobFilter{Sort: "-salary", SalaryMin: 1}, []string{"indeed:hourly", "indeed:euros", "indeed:yearly", "indeed:upto"}},
+	} {
+		page, err := s.Jobs(ctx, tt.filter)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, job := range page.Jobs {
+			got = append(got, job.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
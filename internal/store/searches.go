@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SavedSearch is a finished asynchronous search. The store keeps its state
+// and results as the JSON the API serves, without looking inside.
+type SavedSearch struct {
+	ID         string
+	FinishedAt time.Time
+	State      json.RawMessage
+	Results    json.RawMessage // Nil for searches that didn't complete
+}
+
+// SaveSearch adds or replaces a finished search.
+func (s *Store) SaveSearch(ctx context.Context, search SavedSearch) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO searches (id, finished_at, state, results) VALUES (?, ?, ?, ?)`,
+		search.ID, formatTime(search.FinishedAt), string(search.State), string(search.Results))
+	return err
+}
+
+// Search returns the finished search with the given ID.
+func (s *Store) Search(ctx context.Context, id string) (SavedSearch, error) {
+	search := SavedSearch{ID: id}
+	var finished, state, results string
+	err := s.db.QueryRowContext(ctx, `SELECT finished_at, state, results FROM searches WHERE id = ?`, id).Scan(&finished, &state, &results)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SavedSearch{}, ErrNotFound
+	}
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	search.FinishedAt = parseTime(finished)
+	search.State = json.RawMessage(state)
+	if results != "" {
+		search.Results = json.RawMessage(results)
+	}
+	return search, nil
+}
+
+// DeleteSearches removes the searches that finished before cutoff and
+// returns how many there were.
+func (s *Store) DeleteSearches(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM searches WHERE finished_at < ?`, formatTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
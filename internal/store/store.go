@@ -0,0 +1,522 @@
+// Package store keeps the history of every job the searches have found in
+// an embedded SQLite database.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/health"
+	"job-hunter/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// ErrNotFound is returned when a job or run isn't in the store.
+var ErrNotFound = errors.New("not found")
+
+// Status values of a stored job.
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Job is a stored job with its history.
+type Job struct {
+	models.Job
+	Status      string    `json:"status"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	ClosedAt    time.Time `json:"closed_at,omitzero"`   // When the job was closed, while it is
+	RepostedAt  time.Time `json:"reposted_at,omitzero"` // When the job was last found again after closing
+	RepostCount int       `json:"repost_count,omitempty"`
+	FirstRunID  int64     `json:"first_run_id,omitempty"` // Zero for jobs imported from previous_jobs.txt
+	LastRunID   int64     `json:"last_run_id,omitempty"`
+}
+
+// Run is one recorded search.
+type Run struct {
+	ID            int64     `json:"id"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Title         string    `json:"title"`
+	Location      string    `json:"location"`
+	JobCount      int       `json:"job_count"`
+	NewCount      int       `json:"new_count"`
+	RepostedCount int       `json:"reposted_count"`
+	ClosedCount   int       `json:"closed_count"`
+}
+
+// Changes is what a run changed about the jobs in the store.
+type Changes struct {
+	New      []models.Job // Jobs found for the first time
+	Reposted []models.Job // Closed jobs found again
+	Closed   []Job        // Jobs this run closed
+}
+
+// Options tunes how the store tracks jobs.
+type Options struct {
+	// CloseAfter is how many runs of a search in a row a job must be
+	// missing from, while its sources succeed, before it is closed. Zero
+	// never closes jobs.
+	CloseAfter int
+}
+
+// DefaultOptions returns the options used by the CLI and the API server.
+func DefaultOptions() Options {
+	return Options{CloseAfter: 3}
+}
+
+// Store is a job history database. It is safe for concurrent use.
+type Store struct {
+	db   *sql.DB
+	opts Options
+	now  func() time.Time
+}
+
+// Open opens the database at path, creating it if needed, and brings its
+// schema up to date.
+func Open(path string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening job store: %w", err)
+	}
+	// SQLite allows one writer at a time; a single connection keeps
+	// concurrent searches from failing with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if err := migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating job store %s: %w", path, err)
+	}
+	return &Store{db: db, opts: opts, now: time.Now}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordRun saves the jobs a search found and returns the run along with
+// what it changed. A job is new when none of its listings was found
+// before, including jobs imported from previous_jobs.txt under their old
+// title-based IDs (which are moved to the job's current ID). A closed job
+// found again, under a known listing or as the same company, title and
+// location, is reposted.
+//
+// Jobs of the same search that the run didn't find are closed once they
+// have been missing CloseAfter runs in a row. Only runs in which one of a
+// job's sources succeeded and found something count, so a source failing
+// or breaking never closes its jobs.
+func (s *Store) RecordRun(ctx context.Context, params crawler.JobSearchParams, startedAt time.Time, result *crawler.SearchResult) (Run, Changes, error) {
+	run := Run{
+		StartedAt:  startedAt,
+		FinishedAt: s.now(),
+		Title:      params.Title,
+		Location:   params.Location,
+		JobCount:   len(result.Jobs),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Run{}, Changes{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO runs (started_at, finished_at, title, location, query_key, job_count) VALUES (?, ?, ?, ?, ?, ?)`,
+		formatTime(run.StartedAt), formatTime(run.FinishedAt), run.Title, run.Location, health.QueryKey(params), run.JobCount)
+	if err != nil {
+		return Run{}, Changes{}, fmt.Errorf("recording run: %w", err)
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return Run{}, Changes{}, err
+	}
+	for _, status := range result.Sources {
+		_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO run_sources (run_id, source, ok, job_count) VALUES (?, ?, ?, ?)`,
+			run.ID, status.Name, status.OK() && status.JobCount > 0, status.JobCount)
+		if err != nil {
+			return Run{}, Changes{}, fmt.Errorf("recording run: %w", err)
+		}
+	}
+
+	var changes Changes
+	for _, job := range result.Jobs {
+		kind, err := recordJob(ctx, tx, run, job)
+		if err != nil {
+			return Run{}, Changes{}, fmt.Errorf("recording job %s: %w", job.ID, err)
+		}
+		switch kind {
+		case jobNew:
+			changes.New = append(changes.New, job)
+		case jobReposted:
+			changes.Reposted = append(changes.Reposted, job)
+		}
+	}
+	if changes.Closed, err = s.closeMissing(ctx, tx, run.ID); err != nil {
+		return Run{}, Changes{}, fmt.Errorf("closing jobs: %w", err)
+	}
+
+	run.NewCount, run.RepostedCount, run.ClosedCount = len(changes.New), len(changes.Reposted), len(changes.Closed)
+	_, err = tx.ExecContext(ctx, `UPDATE runs SET new_count = ?, reposted_count = ?, closed_count = ? WHERE id = ?`,
+		run.NewCount, run.RepostedCount, run.ClosedCount, run.ID)
+	if err != nil {
+		return Run{}, Changes{}, fmt.Errorf("recording run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Run{}, Changes{}, fmt.Errorf("recording run: %w", err)
+	}
+	return run, changes, nil
+}
+
+type jobKind int
+
+const (
+	jobSeen jobKind = iota
+	jobNew
+	jobReposted
+)
+
+// recordJob inserts or updates one job and tells how it relates to the
+// jobs already stored.
+func recordJob(ctx context.Context, tx *sql.Tx, run Run, job models.Job) (jobKind, error) {
+	seen := formatTime(run.FinishedAt)
+
+	storedID, err := findJob(ctx, tx, job)
+	if err != nil {
+		return 0, err
+	}
+	if storedID == "" {
+		if storedID, err = findClosedJob(ctx, tx, job); err != nil {
+			return 0, err
+		}
+	}
+
+	kind := jobSeen
+	if storedID == "" {
+		kind = jobNew
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO jobs (
+	id, title, company, location, description, description_html, url, source,
+	salary, salary_min, salary_max, salary_currency, salary_period, employment_type,
+	posted_date, enriched_at, first_seen, last_seen, first_run_id, last_run_id
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			job.ID, job.Title, job.Company, job.Location, job.Description, job.DescriptionHTML, job.URL, job.Source,
+			job.Salary, job.SalaryMin, job.SalaryMax, job.SalaryCurrency, job.SalaryPeriod, job.EmploymentType,
+			formatTime(job.PostedDate), formatTime(job.EnrichedAt), seen, seen, run.ID, run.ID)
+		if err != nil {
+			return 0, err
+		}
+		storedID = job.ID
+	} else {
+		var status string
+		if err := tx.QueryRowContext(ctx, `SELECT status FROM jobs WHERE id = ?`, storedID).Scan(&status); err != nil {
+			return 0, err
+		}
+		if status == StatusClosed {
+			kind = jobReposted
+			_, err := tx.ExecContext(ctx, `
+UPDATE jobs SET status = ?, closed_at = '', fingerprint = '', reposted_at = ?, repost_count = repost_count + 1
+WHERE id = ?`, StatusOpen, seen, storedID)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		// Fields this run didn't get, say because the detail page wasn't
+		// read, keep what earlier runs found.
+		_, err = tx.ExecContext(ctx, `
+UPDATE jobs SET
+	title = COALESCE(NULLIF(?, ''), title),
+	company = COALESCE(NULLIF(?, ''), company),
+	location = COALESCE(NULLIF(?, ''), location),
+	description = COALESCE(NULLIF(?, ''), description),
+	description_html = COALESCE(NULLIF(?, ''), description_html),
+	url = COALESCE(NULLIF(?, ''), url),
+	source = COALESCE(NULLIF(?, ''), source),
+	salary = COALESCE(NULLIF(?, ''), salary),
+	salary_min = COALESCE(NULLIF(?, 0), salary_min),
+	salary_max = COALESCE(NULLIF(?, 0), salary_max),
+	salary_currency = COALESCE(NULLIF(?, ''), salary_currency),
+	salary_period = COALESCE(NULLIF(?, ''), salary_period),
+	employment_type = COALESCE(NULLIF(?, ''), employment_type),
+	posted_date = COALESCE(NULLIF(?, ''), posted_date),
+	enriched_at = COALESCE(NULLIF(?, ''), enriched_at),
+	last_seen = ?,
+	last_run_id = ?,
+	misses = 0
+WHERE id = ?`,
+			job.Title, job.Company, job.Location, job.Description, job.DescriptionHTML, job.URL, job.Source,
+			job.Salary, job.SalaryMin, job.SalaryMax, job.SalaryCurrency, job.SalaryPeriod, job.EmploymentType,
+			formatTime(job.PostedDate), formatTime(job.EnrichedAt), seen, run.ID, storedID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, ref := range listings(job) {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO listings (id, job_id, source, url) VALUES (?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET job_id = excluded.job_id, source = excluded.source, url = excluded.url`,
+			ref.ID, storedID, ref.Source, ref.URL)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Two listings in one run can belong to the same stored job.
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO run_jobs (run_id, job_id, is_new) VALUES (?, ?, ?)`,
+		run.ID, storedID, kind == jobNew)
+	return kind, err
+}
+
+// findJob returns the ID of the stored job that job is a listing of, or ""
+// if there is none.
+func findJob(ctx context.Context, tx *sql.Tx, job models.Job) (string, error) {
+	for _, ref := range listings(job) {
+		var id string
+		err := tx.QueryRowContext(ctx, `SELECT job_id FROM listings WHERE id = ?`, ref.ID).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	for _, key := range crawler.LegacyJobIDs(job) {
+		var id string
+		err := tx.QueryRowContext(ctx, `SELECT job_id FROM listings WHERE legacy_key = ? LIMIT 1`, key).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		// Move the imported job to its current ID. Its old listing goes,
+		// so no other job can match it again.
+		if _, err := tx.ExecContext(ctx, `UPDATE jobs SET id = ? WHERE id = ?`, job.ID, id); err != nil {
+			return "", err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM listings WHERE job_id = ? AND legacy_key != ''`, job.ID); err != nil {
+			return "", err
+		}
+		return job.ID, nil
+	}
+	return "", nil
+}
+
+// findClosedJob returns the ID of the most recently closed job with the
+// same fingerprint as job, or "" if there is none. Sites often repost a
+// job under a new listing ID rather than reopen the old one.
+func findClosedJob(ctx context.Context, tx *sql.Tx, job models.Job) (string, error) {
+	fingerprint := crawler.Fingerprint(job)
+	if fingerprint == "" {
+		return "", nil
+	}
+	var id string
+	err := tx.QueryRowContext(ctx, `
+SELECT id FROM jobs WHERE fingerprint = ? AND status = ? ORDER BY closed_at DESC LIMIT 1`, fingerprint, StatusClosed).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// closeMissing counts a miss against every open job that an earlier run of
+// the same search found, that this run didn't find, and that has a listing
+// on a source that succeeded this run. Imported jobs, which no run has
+// found, count misses in runs of any search, and those whose source isn't
+// known in runs where any source succeeded. It closes the jobs that reach
+// CloseAfter misses and returns them.
+func (s *Store) closeMissing(ctx context.Context, tx *sql.Tx, runID int64) ([]Job, error) {
+	if s.opts.CloseAfter <= 0 {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx, `
+SELECT `+jobColumns+`, j.misses FROM jobs j
+WHERE j.status = ?2
+	AND j.id NOT IN (SELECT job_id FROM run_jobs WHERE run_id = ?1)
+	AND (
+		EXISTS (
+			SELECT 1 FROM run_jobs rj JOIN runs r ON r.id = rj.run_id
+			WHERE rj.job_id = j.id AND r.id != ?1 AND r.query_key = (SELECT query_key FROM runs WHERE id = ?1)
+		)
+		OR NOT EXISTS (SELECT 1 FROM run_jobs rj WHERE rj.job_id = j.id)
+	)
+	AND EXISTS (
+		SELECT 1 FROM listings l JOIN run_sources rs ON rs.source = l.source OR l.source = ''
+		WHERE l.job_id = j.id AND rs.run_id = ?1 AND rs.ok
+	)`, runID, StatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	type missing struct {
+		job    Job
+		misses int
+	}
+	var found []missing
+	for rows.Next() {
+		var m missing
+		var err error
+		if m.job, err = scanJob(rows, &m.misses); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found = append(found, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var closed []Job
+	now := s.now()
+	for _, m := range found {
+		m.misses++
+		if m.misses < s.opts.CloseAfter {
+			if _, err := tx.ExecContext(ctx, `UPDATE jobs SET misses = ? WHERE id = ?`, m.misses, m.job.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE jobs SET misses = ?, status = ?, closed_at = ?, fingerprint = ? WHERE id = ?`,
+			m.misses, StatusClosed, formatTime(now), crawler.Fingerprint(m.job.Job), m.job.ID)
+		if err != nil {
+			return nil, err
+		}
+		m.job.Status, m.job.ClosedAt = StatusClosed, now.UTC()
+		closed = append(closed, m.job)
+	}
+	return closed, nil
+}
+
+// listings returns every listing of job, its own first.
+func listings(job models.Job) []models.SourceRef {
+	refs := []models.SourceRef{{Source: job.Source, ID: job.ID, URL: job.URL}}
+	for _, ref := range job.Sources {
+		if ref.ID != job.ID {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+const jobColumns = `j.id, j.title, j.company, j.location, j.description, j.description_html, j.url, j.source,
+	j.salary, j.salary_min, j.salary_max, j.salary_currency, j.salary_period, j.employment_type,
+	j.posted_date, j.enriched_at, j.status, j.first_seen, j.last_seen, j.closed_at, j.reposted_at, j.repost_count,
+	COALESCE(j.first_run_id, 0), COALESCE(j.last_run_id, 0)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanJob scans the jobColumns of row, followed by any extra columns into
+// extra.
+func scanJob(row scanner, extra ...any) (Job, error) {
+	var job Job
+	var posted, enriched, firstSeen, lastSeen, closed, reposted string
+	dest := []any{&job.ID, &job.Title, &job.Company, &job.Location, &job.Description, &job.DescriptionHTML, &job.URL, &job.Source,
+		&job.Salary, &job.SalaryMin, &job.SalaryMax, &job.SalaryCurrency, &job.SalaryPeriod, &job.EmploymentType,
+		&posted, &enriched, &job.Status, &firstSeen, &lastSeen, &closed, &reposted, &job.RepostCount,
+		&job.FirstRunID, &job.LastRunID}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return Job{}, err
+	}
+	job.PostedDate = parseTime(posted)
+	job.EnrichedAt = parseTime(enriched)
+	job.FirstSeen = parseTime(firstSeen)
+	job.LastSeen = parseTime(lastSeen)
+	job.ClosedAt = parseTime(closed)
+	job.RepostedAt = parseTime(reposted)
+	return job, nil
+}
+
+// Job returns the stored job that id, the ID of any of its listings,
+// belongs to.
+func (s *Store) Job(ctx context.Context, id string) (Job, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs j JOIN listings l ON l.job_id = j.id WHERE l.id = ?`, id)
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	jobs := []Job{job}
+	if err := s.loadSources(ctx, jobs); err != nil {
+		return Job{}, err
+	}
+	return jobs[0], nil
+}
+
+// Runs returns the most recent runs, newest first. A limit of zero or less
+// returns them all.
+func (s *Store) Runs(ctx context.Context, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, started_at, finished_at, title, location, job_count, new_count, reposted_count, closed_count
+FROM runs ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var run Run
+		var started, finished string
+		if err := rows.Scan(&run.ID, &started, &finished, &run.Title, &run.Location, &run.JobCount, &run.NewCount, &run.RepostedCount, &run.ClosedCount); err != nil {
+			return nil, err
+		}
+		run.StartedAt, run.FinishedAt = parseTime(started), parseTime(finished)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// LastRun returns when the last recorded run of the search started, or the
+// zero time if it has never run.
+func (s *Store) LastRun(ctx context.Context, params crawler.JobSearchParams) (time.Time, error) {
+	var started string
+	err := s.db.QueryRowContext(ctx, `SELECT started_at FROM runs WHERE query_key = ? ORDER BY id DESC LIMIT 1`, health.QueryKey(params)).Scan(&started)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return parseTime(started), err
+}
+
+// timeLayout stores times in UTC with a fixed width, so they sort as text
+// and SQLite's date functions can read them.
+const timeLayout = "2006-01-02 15:04:05.000000000"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(timeLayout, s, time.UTC)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
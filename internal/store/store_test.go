@@ -0,0 +1,322 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"job-hunter/internal/crawler"
+	"job-hunter/internal/models"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Expected no error opening the store, got %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// result returns a search result with jobs, in which each of their
+// sources succeeded.
+func result(jobs ...models.Job) *crawler.SearchResult {
+	r := &crawler.SearchResult{Jobs: jobs}
+	counts := make(map[string]int)
+	var names []string
+	for _, job := range jobs {
+		if counts[job.Source] == 0 {
+			names = append(names, job.Source)
+		}
+		counts[job.Source]++
+	}
+	for _, name := range names {
+		r.Sources = append(r.Sources, crawler.SourceStatus{Name: name, JobCount: counts[name]})
+	}
+	return r
+}
+
+func TestRecordRun(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	s := openTestStore(t, path)
+	day1 := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return day1 }
+	params := crawler.JobSearchParams{Title: "Go Developer", Location: "Remote"}
+
+	first := []models.Job{
+		{ID: "indeed:a", Source: "Indeed", Title: "Go Developer", Company: "Hooli", Description: "Full description"},
+		{ID: "linkedin:1", Source: "LinkedIn", Title: "Backend Engineer", Company: "Acme"},
+	}
+	run, changes, err := s.RecordRun(ctx, params, day1.Add(-time.Minute), result(first...))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.ID == 0 || run.JobCount != 2 || run.NewCount != 2 || len(changes.New) != 2 {
+		t.Fatalf("Expected both jobs to be new in the first run, got %+v and %d new", run, len(changes.New))
+	}
+
+	// The next day Indeed's job is found without its description, and
+	// Acme's job is merged with an Indeed listing that is kept instead.
+	day2 := day1.AddDate(0, 0, 1)
+	s.now = func() time.Time { return day2 }
+	second := []models.Job{
+		{ID: "indeed:a", Source: "Indeed", Title: "Go Developer", Company: "Hooli"},
+		{ID: "indeed:b", Source: "Indeed", Title: "Backend Engineer", Company: "Acme", Sources: []models.SourceRef{
+			{Source: "Indeed", ID: "indeed:b"},
+			{Source: "LinkedIn", ID: "linkedin:1"},
+		}},
+		{ID: "indeed:c", Source: "Indeed", Title: "Rust Developer", Company: "Hooli"},
+	}
+	run2, changes, err := s.RecordRun(ctx, params, day2, result(second...))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(changes.New) != 1 || changes.New[0].ID != "indeed:c" || run2.NewCount != 1 {
+		t.Fatalf("Expected only the Rust job to be new, got %+v", changes.New)
+	}
+
+	job, err := s.Job(ctx, "indeed:a")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.Description != "Full description" {
+		t.Errorf("Expected the description from the first run to be kept, got %q", job.Description)
+	}
+	if !job.FirstSeen.Equal(day1) || !job.LastSeen.Equal(day2) || job.FirstRunID != run.ID || job.LastRunID != run2.ID {
+		t.Errorf("Expected first seen on day 1 and last seen on day 2, got %+v", job)
+	}
+
+	merged, err := s.Job(ctx, "indeed:b")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if merged.ID != "linkedin:1" || len(merged.Sources) != 2 || merged.Sources[0].ID != "linkedin:1" {
+		t.Errorf("Expected the new listing to join the stored job, got %+v", merged)
+	}
+
+	if _, err := s.Job(ctx, "glassdoor:9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	runs, err := s.Runs(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != run2.ID || runs[0].Title != "Go Developer" || runs[1].NewCount != 2 {
+		t.Errorf("Expected both runs newest first, got %+v", runs)
+	}
+	if last, err := s.LastRun(ctx, params); err != nil || !last.Equal(day2) {
+		t.Errorf("Expected the last run to have started on day 2, got %v, %v", last, err)
+	}
+	if last, err := s.LastRun(ctx, crawler.JobSearchParams{Title: "Rust Developer"}); err != nil || !last.IsZero() {
+		t.Errorf("Expected no last run of another search, got %v, %v", last, err)
+	}
+
+	// Reopening doesn't migrate again or lose anything.
+	s.Close()
+	s = openTestStore(t, path)
+	if _, err := s.Job(ctx, "indeed:c"); err != nil {
+		t.Errorf("Expected the job to survive reopening, got %v", err)
+	}
+}
+
+func TestImportPreviousJobs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := openTestStore(t, filepath.Join(dir, "jobs.db"))
+
+	// A file from before native IDs, including a job spread over several
+	// lines by a title with newlines in it.
+	path := filepath.Join(dir, "previous_jobs.txt")
+	legacy := "linkedin-%0A++Senior+Director%2C+Enterprise+Data%0A++-%0A++\t\n" +
+		"        Senior Director, Enterprise Data\n" +
+		"          \thttps://www.linkedin.com/company/qualcomm\n" +
+		"indeed-Go+Developer-Hooli\tGo Developer\tHooli\thttps://www.indeed.com/rc/clk?jk=0a9b8c7d6e5f4a3b\n" +
+		"glassdoor:77\tData Engineer\tInitech\thttps://www.glassdoor.com/job-listing/x?jl=77\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	added, err := s.ImportPreviousJobs(ctx, path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if added != 3 {
+		t.Fatalf("Expected 3 imported jobs, got %d", added)
+	}
+	if added, err := s.ImportPreviousJobs(ctx, path); err != nil || added != 0 {
+		t.Errorf("Expected importing again to add nothing, got %d, %v", added, err)
+	}
+
+	current := []models.Job{
+		{ID: "linkedin:3891234567", Source: "LinkedIn", Title: "Senior Director, Enterprise Data", Company: "Qualcomm"},
+		{ID: "indeed:0a9b8c7d6e5f4a3b", Source: "Indeed", Title: "Go Developer", Company: "Hooli"},
+		{ID: "indeed:1b2c3d4e5f6a7b8c", Source: "Indeed", Title: "Rust Developer", Company: "Hooli"},
+		{ID: "glassdoor:77", Source: "Glassdoor", Title: "Data Engineer", Company: "Initech"},
+	}
+	_, changes, err := s.RecordRun(ctx, crawler.JobSearchParams{Title: "engineer"}, time.Now(), result(current...))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(changes.New) != 1 || changes.New[0].Title != "Rust Developer" {
+		t.Fatalf("Expected only the Rust job to be new, got %+v", changes.New)
+	}
+
+	// The imported job now goes by its current ID, and keeps its history.
+	job, err := s.Job(ctx, "indeed:0a9b8c7d6e5f4a3b")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.ID != "indeed:0a9b8c7d6e5f4a3b" || job.FirstRunID != 0 || job.Sources != nil {
+		t.Errorf("Expected the legacy job to be moved to its current ID, got %+v", job)
+	}
+	if _, err := s.Job(ctx, "indeed-Go+Developer-Hooli"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the legacy ID to be gone, got %v", err)
+	}
+}
+
+func TestImportedJobsClose(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := openTestStore(t, filepath.Join(dir, "jobs.db"))
+
+	path := filepath.Join(dir, "previous_jobs.txt")
+	legacy := "indeed-Go+Developer-Hooli\tGo Developer\tHooli\thttps://www.indeed.com/rc/clk?jk=1\n" +
+		"linkedin-Backend+Engineer-Acme\tBackend Engineer\tAcme\thttps://www.linkedin.com/jobs/view/2\n" +
+		"Go+Developer-Initech\tGo Developer\tInitech\thttps://example.com/jobs/3\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ImportPreviousJobs(ctx, path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	job, err := s.Job(ctx, "indeed-Go+Developer-Hooli")
+	if err != nil || job.Source != "Indeed" {
+		t.Fatalf("Expected the imported listing to be on Indeed, got %+v, %v", job, err)
+	}
+
+	// Indeed succeeds without finding the imported jobs again.
+	current := models.Job{ID: "indeed:9", Source: "Indeed", Title: "Rust Developer", Company: "Hooli"}
+	var closed []string
+	for range DefaultOptions().CloseAfter {
+		_, changes, err := s.RecordRun(ctx, crawler.JobSearchParams{Title: "developer"}, time.Now(), result(current))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, job := range changes.Closed {
+			closed = append(closed, job.ID)
+		}
+	}
+	want := []string{"Go+Developer-Initech", "indeed-Go+Developer-Hooli"}
+	slices.Sort(closed)
+	if !slices.Equal(closed, want) {
+		t.Errorf("Expected the Indeed and unknown-source imports to close, got %v", closed)
+	}
+	if job, err := s.Job(ctx, "linkedin-Backend+Engineer-Acme"); err != nil || job.Status != StatusOpen {
+		t.Errorf("Expected the LinkedIn import to stay open while LinkedIn isn't searched, got %+v, %v", job, err)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+	day := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+	params := crawler.JobSearchParams{Title: "Go Developer"}
+	record := func(r *crawler.SearchResult) Changes {
+		t.Helper()
+		day = day.AddDate(0, 0, 1)
+		s.now = func() time.Time { return day }
+		_, changes, err := s.RecordRun(ctx, params, day, r)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return changes
+	}
+
+	goJob := models.Job{ID: "indeed:a", Source: "Indeed", Title: "Go Developer", Company: "Hooli", Location: "Austin, TX"}
+	other := models.Job{ID: "indeed:b", Source: "Indeed", Title: "Rust Developer", Company: "Hooli"}
+	linkedIn := models.Job{ID: "linkedin:1", Source: "LinkedIn", Title: "Backend Engineer", Company: "Acme"}
+	record(result(goJob, other, linkedIn))
+
+	// LinkedIn failing, then coming back, neither closes its job nor makes
+	// it new.
+	failed := result(goJob, other)
+	failed.Sources = append(failed.Sources, crawler.SourceStatus{Name: "LinkedIn", ErrorClass: crawler.ErrorClassHTTPStatus, StatusCode: 403, Blocked: true})
+	for range 3 {
+		if changes := record(failed); len(changes.Closed) != 0 || len(changes.New) != 0 {
+			t.Fatalf("Expected a failed source to change nothing, got %+v", changes)
+		}
+	}
+	if changes := record(result(goJob, other, linkedIn)); len(changes.New) != 0 || len(changes.Closed) != 0 {
+		t.Fatalf("Expected the LinkedIn job to still be known, got %+v", changes)
+	}
+
+	// A search for something else doesn't count against the job either,
+	// but three runs of its own search without it close it.
+	if _, _, err := s.RecordRun(ctx, crawler.JobSearchParams{Title: "Rust"}, day, result(other)); err != nil {
+		t.Fatal(err)
+	}
+	if changes := record(result(other, linkedIn)); len(changes.Closed) != 0 {
+		t.Fatalf("Expected one miss not to close the job, got %+v", changes.Closed)
+	}
+	record(result(other, linkedIn))
+	changes := record(result(other, linkedIn))
+	if len(changes.Closed) != 1 || changes.Closed[0].ID != "indeed:a" || changes.Closed[0].Status != StatusClosed {
+		t.Fatalf("Expected the Go job to close after 3 misses, got %+v", changes.Closed)
+	}
+	closedAt := day
+
+	// It comes back under a new listing ID, with the company and location
+	// written a little differently.
+	repost := models.Job{ID: "indeed:z", Source: "Indeed", Title: "Go Developer", Company: "Hooli Inc.", Location: "Austin, Texas"}
+	changes = record(result(repost, other, linkedIn))
+	if len(changes.Reposted) != 1 || changes.Reposted[0].ID != "indeed:z" || len(changes.New) != 0 {
+		t.Fatalf("Expected the job to be reposted rather than new, got %+v", changes)
+	}
+	job, err := s.Job(ctx, "indeed:z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != "indeed:a" || job.Status != StatusOpen || job.RepostCount != 1 || !job.RepostedAt.Equal(day) || !job.ClosedAt.IsZero() {
+		t.Errorf("Expected the stored job to be open again, got %+v", job)
+	}
+	if job.LastSeen.Before(closedAt) || len(job.Sources) != 2 {
+		t.Errorf("Expected the new listing to be added, got %+v", job)
+	}
+}
+
+func TestSavedSearches(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+	day := time.Date(2025, 4, 14, 8, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"old", "new"} {
+		search := SavedSearch{ID: id, FinishedAt: day.AddDate(0, 0, i), State: []byte(`{"state":"completed"}`)}
+		if id == "new" {
+			search.Results = []byte(`{"jobs":[]}`)
+		}
+		if err := s.SaveSearch(ctx, search); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	search, err := s.Search(ctx, "new")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(search.Results) != `{"jobs":[]}` || !search.FinishedAt.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the saved search back, got %+v", search)
+	}
+
+	if n, err := s.DeleteSearches(ctx, day.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("Expected the old search to be deleted, got %d, %v", n, err)
+	}
+	if _, err := s.Search(ctx, "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}